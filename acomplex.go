@@ -22,11 +22,35 @@ func New(r float64, i float64) *AComplex {
 	}
 }
 
+// NewFromString parses r and i as decimals whose arithmetic is rounded to
+// precision significant digits. It reports false if either is not a number.
+func NewFromString(r string, i string, precision int) (*AComplex, bool) {
+	var real decimal.Big
+	real.Context.Precision = precision
+	if _, ok := real.SetString(r); !ok || !real.IsFinite() {
+		return nil, false
+	}
+
+	var img decimal.Big
+	img.Context.Precision = precision
+	if _, ok := img.SetString(i); !ok || !img.IsFinite() {
+		return nil, false
+	}
+
+	return &AComplex{
+		r: real,
+		i: img,
+	}, true
+}
+
+// Results of Add and Mul keep the precision of their first operand.
 func Add(a AComplex, other AComplex) AComplex {
 	var real decimal.Big
+	real.Context = a.r.Context
 	real.Add(&a.r, &other.r)
 
 	var img decimal.Big
+	img.Context = a.i.Context
 	img.Add(&a.i, &other.i)
 
 	return AComplex{
@@ -36,22 +60,30 @@ func Add(a AComplex, other AComplex) AComplex {
 }
 
 func Mul(a AComplex, other AComplex) AComplex {
+	ctx := a.r.Context
+
 	var x1x2 decimal.Big
+	x1x2.Context = ctx
 	x1x2.Mul(&a.r, &other.r)
 
 	var y1y2 decimal.Big
+	y1y2.Context = ctx
 	y1y2.Mul(&a.i, &other.i)
 
 	var x1y2 decimal.Big
+	x1y2.Context = ctx
 	x1y2.Mul(&a.r, &other.i)
 
 	var x2y1 decimal.Big
+	x2y1.Context = ctx
 	x2y1.Mul(&a.i, &other.r)
 
 	var real decimal.Big
+	real.Context = ctx
 	real.Sub(&x1x2, &y1y2)
 
 	var img decimal.Big
+	img.Context = ctx
 	img.Add(&x1y2, &x2y1)
 
 	// fmt.Println(x1.String(), y1.String(), x2.String(), y2.String(), x1x2.String(), y1y2.String(), x1y2.String(), x2y1.String())
@@ -63,14 +95,40 @@ func Mul(a AComplex, other AComplex) AComplex {
 }
 
 func Gt2(a AComplex) bool {
-	x := a.r
-	y := a.i
+	// Square into fresh values: copies of a.r and a.i share their mantissa
+	// with a, so multiplying them in place would corrupt a.
+	var x decimal.Big
+	x.Context = a.r.Context
+	x.Mul(&a.r, &a.r)
 
-	x.Mul(&x, &x)
-	y.Mul(&y, &y)
+	var y decimal.Big
+	y.Context = a.i.Context
+	y.Mul(&a.i, &a.i)
 
 	var four decimal.Big
 	four.SetFloat64(4)
 
 	return x.Add(&x, &y).Cmp(&four) > 0
 }
+
+// GtR reports whether |a| > r.
+func GtR(a AComplex, r float64) bool {
+	var x decimal.Big
+	x.Context = a.r.Context
+	x.Mul(&a.r, &a.r)
+
+	var y decimal.Big
+	y.Context = a.i.Context
+	y.Mul(&a.i, &a.i)
+
+	var r2 decimal.Big
+	r2.SetFloat64(r * r)
+
+	return x.Add(&x, &y).Cmp(&r2) > 0
+}
+
+func Complex128(a AComplex) complex128 {
+	r, _ := a.r.Float64()
+	i, _ := a.i.Float64()
+	return complex(r, i)
+}
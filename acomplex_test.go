@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math/cmplx"
+	"strings"
+	"testing"
+)
+
+var acomplexValues = []complex128{0, 1, -1, 1i, 0.5 - 0.25i, -0.75 + 0.1i, 3.5 + 2i, -1e-5 + 2e-6i, 1234.5 - 678.25i}
+
+// closeTo reports whether got matches want up to float64 rounding.
+func closeTo(got, want complex128) bool {
+	return cmplx.Abs(got-want) <= 1e-12*max(1, cmplx.Abs(want))
+}
+
+func TestAComplexAdd(t *testing.T) {
+	for _, a := range acomplexValues {
+		for _, b := range acomplexValues {
+			if got := Complex128(Add(*New(real(a), imag(a)), *New(real(b), imag(b)))); !closeTo(got, a+b) {
+				t.Errorf("Add(%v, %v) = %v, want %v", a, b, got, a+b)
+			}
+		}
+	}
+}
+
+func TestAComplexMul(t *testing.T) {
+	for _, a := range acomplexValues {
+		for _, b := range acomplexValues {
+			if got := Complex128(Mul(*New(real(a), imag(a)), *New(real(b), imag(b)))); !closeTo(got, a*b) {
+				t.Errorf("Mul(%v, %v) = %v, want %v", a, b, got, a*b)
+			}
+		}
+	}
+}
+
+// TestAComplexIteration follows an orbit of z = z² + c next to complex128,
+// which it must track while neither has lost much precision.
+func TestAComplexIteration(t *testing.T) {
+	c := -0.75 + 0.1i
+	z, exact := c, *New(real(c), imag(c))
+	for range 20 {
+		z = z*z + c
+		exact = Add(Mul(exact, exact), *New(real(c), imag(c)))
+	}
+	if got := Complex128(exact); cmplx.Abs(got-z) > 1e-9 {
+		t.Errorf("orbit ended at %v, want %v", got, z)
+	}
+}
+
+func TestAComplexGtR(t *testing.T) {
+	for _, a := range acomplexValues {
+		for _, r := range []float64{0.5, 1, 2, 100} {
+			want := cmplx.Abs(a) > r
+			if got := GtR(*New(real(a), imag(a)), r); got != want {
+				t.Errorf("GtR(%v, %v) = %v, want %v", a, r, got, want)
+			}
+		}
+		if got, want := Gt2(*New(real(a), imag(a))), cmplx.Abs(a) > 2; got != want {
+			t.Errorf("Gt2(%v) = %v, want %v", a, got, want)
+		}
+	}
+}
+
+// TestAComplexGtRKeepsOperand makes sure comparing does not square a in
+// place.
+func TestAComplexGtRKeepsOperand(t *testing.T) {
+	a := *New(1.5, -0.5)
+	GtR(a, 2)
+	Gt2(a)
+	if got := Complex128(a); got != 1.5-0.5i {
+		t.Errorf("a is %v after comparing, want %v", got, 1.5-0.5i)
+	}
+}
+
+// TestAComplexPrecision checks that arithmetic keeps the digits of its first
+// operand beyond float64, as deep zooms rely on.
+func TestAComplexPrecision(t *testing.T) {
+	a, ok := NewFromString("1", "0.00000000000000000001", 50)
+	if !ok {
+		t.Fatal("NewFromString rejected valid decimals")
+	}
+
+	// (1 + εi)² = 1 - ε² + 2εi, whose real part float64 rounds to 1.
+	square := Mul(*a, *a)
+	if got, want := square.r.String(), "0."+strings.Repeat("9", 40); got != want {
+		t.Errorf("real part is %s, want %s", got, want)
+	}
+	if got, want := square.i.String(), "2E-20"; got != want {
+		t.Errorf("imaginary part is %s, want %s", got, want)
+	}
+}
+
+func TestNewFromStringRejectsNonNumbers(t *testing.T) {
+	for _, value := range [][2]string{{"abc", "0"}, {"0", "1.2.3"}, {"inf", "0"}, {"0", "NaN"}} {
+		if _, ok := NewFromString(value[0], value[1], 30); ok {
+			t.Errorf("NewFromString(%q, %q) accepted", value[0], value[1])
+		}
+	}
+}
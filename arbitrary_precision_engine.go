@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"math"
+	"math/cmplx"
+
+	"github.com/ericlagergren/decimal"
+)
+
+type ArbitraryPrecisionEngine struct {
+	*engineCore
+
+	z []AComplex
+
+	scale                      decimal.Big
+	scaleFactorX, scaleFactorY decimal.Big
+	precision                  int
+
+	center AComplex
+	// rotation is cos θ + i·sin θ for the view angle θ, nil if unrotated.
+	rotation *AComplex
+
+	c AComplex
+}
+
+type ArbitraryPrecisionEngineParams struct {
+	EngineParams
+	// The view in decimal, which takes the place of the float64 CenterX,
+	// CenterY and Scale beyond their precision and range.
+	DecimalCenterX, DecimalCenterY *string
+	DecimalScale                   *string
+}
+
+// precisionForScale picks enough significant digits to tell neighbouring
+// pixels apart at the given zoom, plus guard digits for the iteration.
+func precisionForScale(width int, scale *decimal.Big) int {
+	// The adjusted exponent of the scale is its order of magnitude, which
+	// stays exact where a float64 would overflow.
+	magnitude := max(scale.Precision()-scale.Scale(), 0)
+	return 16 + int(math.Ceil(math.Log10(float64(width)))) + magnitude
+}
+
+// parseScale reads a zoom level of any magnitude, falling back to 1.
+func parseScale(value string) *decimal.Big {
+	scale, ok := new(decimal.Big).SetString(value)
+	if !ok || scale.Sign() <= 0 {
+		return decimal.New(1, 0)
+	}
+	return scale
+}
+
+func init() {
+	RegisterEngine(EngineDefinition{
+		Name:     "arbitrary",
+		Julia:    true,
+		DeepZoom: true,
+		New: func(params cliParams) Engine {
+			return NewArbitraryPrecisionEngine(ArbitraryPrecisionEngineParams{
+				EngineParams:   params.engineParamsWithoutView(),
+				DecimalCenterX: &params.centerX,
+				DecimalCenterY: &params.centerY,
+				DecimalScale:   &params.scale,
+			})
+		},
+	})
+}
+
+func NewArbitraryPrecisionEngine(params ArbitraryPrecisionEngineParams) *ArbitraryPrecisionEngine {
+	scale := parseScale(Elvis(params.DecimalScale, "1"))
+	precision := precisionForScale(params.Width, scale)
+
+	center, ok := NewFromString(Elvis(params.DecimalCenterX, "-0.75"), Elvis(params.DecimalCenterY, "0"), precision)
+	if !ok {
+		center = New(-0.75, 0)
+	}
+
+	c := New(Elvis(params.CReal, 0), Elvis(params.CImag, 0))
+	zero := AComplex{}
+	zero.r.Context.Precision = precision
+	zero.i.Context.Precision = precision
+
+	// The core only maps pixels to the plane for the float64 approximation
+	// of the view, pixel does so in full precision.
+	centerX, centerY := real(Complex128(*center)), imag(Complex128(*center))
+	floatScale, _ := scale.Float64()
+
+	core := params.EngineParams
+	core.CenterX, core.CenterY, core.Scale = &centerX, &centerY, &floatScale
+
+	engine := ArbitraryPrecisionEngine{
+		engineCore: newEngineCore(core),
+		scale:      *scale,
+		precision:  precision,
+		center:     *center,
+		c:          *c,
+	}
+	engine.z = newPixelsWithValue(&engine.chunkGrid, zero)
+
+	// scaleFactorX = scaleFactorY = 3 / (width * scale), pixels are square.
+	var span decimal.Big
+	span.Context.Precision = precision
+	span.Mul(decimal.New(int64(params.Width), 0), scale)
+
+	engine.scaleFactorX.Context.Precision = precision
+	engine.scaleFactorX.Quo(decimal.New(3, 0), &span)
+
+	engine.scaleFactorY.Context.Precision = precision
+	engine.scaleFactorY.Copy(&engine.scaleFactorX)
+
+	if angle := Elvis(params.Angle, 0); angle != 0 {
+		engine.rotation = New(rotationOf(angle))
+	}
+
+	engine.seedJulia(func(p int, px, py int32) {
+		engine.z[p] = engine.pixel(px, py)
+	})
+
+	return &engine
+}
+
+// pixel returns the point of the complex plane under pixel (XX, YY).
+func (f *ArbitraryPrecisionEngine) pixel(XX, YY int32) AComplex {
+	var offset AComplex
+	offset.r.Context.Precision = f.precision
+	offset.r.Mul(decimal.New(int64(XX-int32(f.width/2)), 0), &f.scaleFactorX)
+	offset.i.Context.Precision = f.precision
+	offset.i.Mul(decimal.New(int64(YY-int32(f.height/2)), 0), &f.scaleFactorY)
+
+	if f.rotation != nil {
+		offset = Mul(offset, *f.rotation)
+	}
+
+	return Add(f.center, offset)
+}
+
+func (f *ArbitraryPrecisionEngine) Perform(context context.Context, x, y int32) {
+	f.performChunk(context, x, y, func(p int, px, py int32) {
+		c := f.c
+		if !f.julia {
+			c = f.pixel(px, py)
+		}
+
+		z := f.z[p]
+		for i := range f.subIterations {
+			if GtR(z, f.escapeRadius) {
+				f.escape(p, i, cmplx.Abs(Complex128(z)))
+				break
+			}
+
+			z = Add(Mul(z, z), c)
+		}
+		f.z[p] = z
+	})
+}
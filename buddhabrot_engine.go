@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// buddhabrotSampleRadius bounds the square c is sampled from, whatever the
+// view. The orbits passing through any view start from all over the set,
+// which lies within it, so zooming in leaves fewer hits per pixel rather
+// than sampling closer to the view.
+const buddhabrotSampleRadius = 2
+
+// BuddhabrotEngine plots where the orbits of randomly sampled points c go
+// rather than how fast c escapes. Every channel counts the orbits escaping
+// within its own iteration limit, which gives the Nebulabrot when the limits
+// differ; the anti-Buddhabrot counts the orbits that never escape instead.
+//
+// Unlike the escape-time engines, a chunk's orbits land anywhere in the
+// image. Chunks therefore trace into a buffer of the slot they run in, and
+// FinishIteration adds the buffers to the density once all are done. Every
+// iteration samples each chunk once, a single sub-iteration of the core.
+type BuddhabrotEngine struct {
+	*engineCore
+
+	// density holds the hits of every pixel for the red, green and blue
+	// channel in turn.
+	density    []uint32
+	maxDensity [3]uint32
+
+	// buffers holds a buffer for each of the slots free hands out, allocated
+	// by the first chunk running in the slot.
+	buffers [][]uint32
+	free    chan int
+
+	limits [3]int
+	nebula bool
+	anti   bool
+}
+
+type BuddhabrotEngineParams struct {
+	EngineParams
+	// Limits are the iteration limits of the red, green and blue channel.
+	Limits *[3]int
+	// Nebula colors every channel by its own density instead of coloring the
+	// density of the first one with the palette.
+	Nebula *bool
+	Anti   *bool
+}
+
+// ParseChannelLimits reads either one iteration limit shared by all channels
+// or three comma separated ones for the red, green and blue channel, in
+// which case it reports a Nebulabrot.
+func ParseChannelLimits(text string) ([3]int, bool, error) {
+	var limits [3]int
+
+	fields := strings.Split(text, ",")
+	if len(fields) != 1 && len(fields) != 3 {
+		return limits, false, fmt.Errorf("expected one or three limits in %q", text)
+	}
+
+	for k := range limits {
+		limit, err := strconv.Atoi(strings.TrimSpace(fields[k%len(fields)]))
+		if err != nil || limit <= 0 {
+			return limits, false, fmt.Errorf("invalid limit %q", fields[k%len(fields)])
+		}
+		limits[k] = limit
+	}
+	return limits, len(fields) == 3, nil
+}
+
+func init() {
+	RegisterEngine(EngineDefinition{
+		Name: "buddhabrot",
+		Flags: func(flags *flag.FlagSet, params *cliParams) {
+			flags.StringVar(&params.limits, "limits", "2000,200,20", "iteration limit of the buddhabrot engine, or three comma separated ones for the red, green and blue channel of a Nebulabrot")
+			flags.BoolVar(&params.anti, "anti", false, "trace the orbits that never escape instead, the anti-Buddhabrot (buddhabrot engine)")
+		},
+		Verify: func(params cliParams) error {
+			if _, _, err := ParseChannelLimits(params.limits); err != nil {
+				return fmt.Errorf("invalid limits: %w", err)
+			}
+			return nil
+		},
+		New: func(params cliParams) Engine {
+			limits, nebula, _ := ParseChannelLimits(params.limits)
+			return NewBuddhabrotEngine(BuddhabrotEngineParams{
+				EngineParams: params.engineParams(),
+				Limits:       &limits,
+				Nebula:       &nebula,
+				Anti:         &params.anti,
+			})
+		},
+	})
+}
+
+func NewBuddhabrotEngine(params BuddhabrotEngineParams) *BuddhabrotEngine {
+	// Every iteration traces one batch of orbits, up to the limits.
+	core := params.EngineParams
+	core.SubIterations = Ptr(1)
+
+	engine := BuddhabrotEngine{
+		engineCore: newEngineCoreWithoutEscapes(core),
+		density:    make([]uint32, 3*params.Width*params.Height),
+		limits:     Elvis(params.Limits, [3]int{2000, 200, 20}),
+		nebula:     Elvis(params.Nebula, true),
+		anti:       Elvis(params.Anti, false),
+	}
+	engine.buffers = make([][]uint32, runtime.GOMAXPROCS(0))
+	engine.free = make(chan int, len(engine.buffers))
+	for slot := range engine.buffers {
+		engine.free <- slot
+	}
+
+	return &engine
+}
+
+// inMainBulbs tells whether c lies in the main cardioid or the period two
+// bulb, whose points never escape.
+func inMainBulbs(cr, ci float64) bool {
+	q := (cr-0.25)*(cr-0.25) + ci*ci
+	if q*(q+cr-0.25) <= ci*ci/4 {
+		return true
+	}
+	return (cr+1)*(cr+1)+ci*ci <= 1.0/16
+}
+
+// Perform traces as many orbits as the chunk has pixels into the buffer of a
+// free slot, waiting for one if there is none. The chunk only seeds the
+// samples, as orbits land all over the image.
+func (f *BuddhabrotEngine) Perform(context context.Context, x, y int32) {
+	slot := <-f.free
+	defer func() {
+		f.free <- slot
+	}()
+
+	if f.buffers[slot] == nil {
+		f.buffers[slot] = make([]uint32, len(f.density))
+	}
+	local := f.buffers[slot]
+
+	random := rand.New(rand.NewPCG(uint64(f.iterations), uint64(y)<<32|uint64(x)))
+	maxLimit := max(f.limits[0], f.limits[1], f.limits[2])
+	orbit := make([]complex128, 0, maxLimit)
+
+	for range f.chunkSizeX * f.chunkSizeY {
+		select {
+		case <-context.Done():
+			return
+
+		default:
+		}
+
+		cr := (2*random.Float64() - 1) * buddhabrotSampleRadius
+		ci := (2*random.Float64() - 1) * buddhabrotSampleRadius
+		if !f.anti && inMainBulbs(cr, ci) {
+			continue
+		}
+
+		// The orbit leaves out z₁ = c, which only shows the uniform sampling
+		// as a flat disc.
+		orbit = orbit[:0]
+		zr, zi := cr, ci
+		escapedAt := math.MaxInt
+		for i := range maxLimit {
+			zr, zi = zr*zr-zi*zi+cr, 2*zr*zi+ci
+			if zr*zr+zi*zi > 4 {
+				escapedAt = i
+				break
+			}
+			orbit = append(orbit, complex(zr, zi))
+		}
+
+		for k, limit := range f.limits {
+			var points []complex128
+			if !f.anti && escapedAt < limit {
+				points = orbit
+			} else if f.anti && escapedAt >= limit {
+				points = orbit[:limit]
+			}
+
+			for _, z := range points {
+				px, py := f.viewport.ToPixel(real(z), imag(z))
+				if px < 0 || py < 0 || px >= float64(f.width) || py >= float64(f.height) {
+					continue
+				}
+				local[3*(int(py)*f.width+int(px))+k]++
+			}
+		}
+	}
+}
+
+// FinishIteration adds the buffers the chunks traced into to the density.
+func (f *BuddhabrotEngine) FinishIteration() {
+	for _, local := range f.buffers {
+		for i, hits := range local {
+			if hits != 0 {
+				f.density[i] += hits
+				f.maxDensity[i%3] = max(f.maxDensity[i%3], f.density[i])
+			}
+		}
+		clear(local)
+	}
+}
+
+// GetExplodesAt returns how many orbits passed through the pixel within the
+// first channel's limit.
+func (f *BuddhabrotEngine) GetExplodesAt(x, y int32) int {
+	return int(f.density[3*(int(y)*f.width+int(x))])
+}
+
+func (f *BuddhabrotEngine) GetSmoothExplodesAt(x, y int32) float64 {
+	return float64(f.GetExplodesAt(x, y))
+}
+
+// GetColor brightens every channel with its density relative to the densest
+// pixel of that channel.
+func (f *BuddhabrotEngine) GetColor(x, y int32, colorPicker ColorOf) color.RGBA {
+	i := 3 * (int(y)*f.width + int(x))
+
+	var level [3]float64
+	for k := range level {
+		if f.maxDensity[k] > 0 {
+			level[k] = float64(f.density[i+k]) / float64(f.maxDensity[k])
+		}
+	}
+
+	if !f.nebula {
+		if level[0] == 0 {
+			return color.RGBA{A: 255}
+		}
+		// Palettes take levels in [0, 1), as the range converters give them,
+		// so keep the densest pixel just below 1.
+		return colorPicker.Get(min(level[0], math.Nextafter(1, 0)))
+	}
+	return color.RGBA{R: uint8(255 * level[0]), G: uint8(255 * level[1]), B: uint8(255 * level[2]), A: 255}
+}
+
+func (f *BuddhabrotEngine) GetMaxExplodesAt() int {
+	return int(f.maxDensity[0])
+}
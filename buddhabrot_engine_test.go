@@ -0,0 +1,23 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+// TestBuddhabrotColorsTheDensestPixel checks that the densest pixel takes the
+// bright end of the palette rather than a color off its end.
+func TestBuddhabrotColorsTheDensestPixel(t *testing.T) {
+	histogram := NewHistogram("gradient.png")
+	engine := NewBuddhabrotEngine(BuddhabrotEngineParams{
+		EngineParams: EngineParams{Width: 4, Height: 4},
+		Nebula:       Ptr(false),
+	})
+	engine.density[0] = 10
+	engine.maxDensity[0] = 10
+
+	r, g, b, a := histogram.file.At(histogram.width-1, 0).RGBA()
+	if got, want := engine.GetColor(0, 0, histogram), (color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: uint8(a)}); got != want {
+		t.Errorf("densest pixel is %v, want the last color of the gradient %v", got, want)
+	}
+}
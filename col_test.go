@@ -0,0 +1,75 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestSpectralColorIsOpaqueAndContinuous(t *testing.T) {
+	previous := SpectralColor{}.Get(0)
+	for k := range 1001 {
+		arg := float64(k) / 1000
+		c := SpectralColor{}.Get(arg)
+		if c.A != 255 {
+			t.Fatalf("Get(%v) = %v is not opaque", arg, c)
+		}
+
+		// A channel overflowing uint8 would wrap around and jump.
+		for _, step := range [][2]uint8{{previous.R, c.R}, {previous.G, c.G}, {previous.B, c.B}} {
+			if diff := int(step[1]) - int(step[0]); diff > 8 || diff < -8 {
+				t.Fatalf("Get(%v) = %v jumps from %v", arg, c, previous)
+			}
+		}
+		previous = c
+	}
+}
+
+func TestSpectralColorHues(t *testing.T) {
+	if c := (SpectralColor{}).Get(0.5); c.G <= c.R || c.G <= c.B {
+		t.Errorf("middle of the spectrum is %v, want green", c)
+	}
+	if c := (SpectralColor{}).Get(0.8); c.R <= c.G || c.R <= c.B {
+		t.Errorf("upper end of the spectrum is %v, want red", c)
+	}
+}
+
+func TestHistogramSamplesTheGradient(t *testing.T) {
+	histogram := NewHistogram("gradient.png")
+
+	r, g, b, a := histogram.file.At(0, 0).RGBA()
+	if got, want := histogram.Get(0), (color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: uint8(a)}); got != want {
+		t.Errorf("Get(0) = %v, want the first pixel %v", got, want)
+	}
+
+	// Converters return [0, 1), all of which lies on the gradient.
+	for k := range 1000 {
+		if c := histogram.Get(float64(k) / 1000); c.A == 0 {
+			t.Fatalf("Get(%v) = %v lies off the gradient", float64(k)/1000, c)
+		}
+	}
+}
+
+func TestExponentialConverterRange(t *testing.T) {
+	for _, converter := range []ExponentialMappedModuloColorRangeConverer{
+		{S: 1.1, Steps: 20},
+		{S: 1, Steps: 1},
+		{S: 0.5, Steps: 7},
+		{S: 2, Steps: 100},
+	} {
+		for _, h := range []float64{1, 50, 12345} {
+			for k := range 101 {
+				l := h * float64(k) / 100
+				if fac := converter.Get(l, h); !(fac >= 0 && fac < 1) {
+					t.Errorf("%+v.Get(%v, %v) = %v, outside [0, 1)", converter, l, h, fac)
+				}
+			}
+		}
+	}
+}
+
+func TestExponentialConverterStartsAtZero(t *testing.T) {
+	converter := ExponentialMappedModuloColorRangeConverer{S: 1.1, Steps: 20}
+	if fac := converter.Get(0, 100); fac != 0 {
+		t.Errorf("Get(0, 100) = %v, want 0", fac)
+	}
+}
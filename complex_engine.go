@@ -2,145 +2,65 @@ package main
 
 import (
 	"context"
-	"image"
+	"math"
 )
 
 type ComplexEngine struct {
-	fz            [][][][]complex128
-	fz2           [][][][]complex128
-	explodesAt    [][][][]int
-	image         *image.RGBA
-	maxExplodesAt int
+	*engineCore
 
-	width, height              int
-	scale                      int
-	scaleFactorX, scaleFactorY float64
-
-	centerX, centerY float64
-
-	subIterations int
-
-	chunkSizeX, chunkSizeY int
-
-	iterations int
-
-	stopped bool
+	fz  []complex128
+	fz2 []complex128
 }
 
 type ComplexEngineParams struct {
-	Width, Height          int
-	CenterX, CenterY       *float64
-	Scale                  *int
-	SubIterations          *int
-	ChunkSizeX, ChunkSizeY *int
+	EngineParams
+}
+
+func init() {
+	RegisterEngine(EngineDefinition{
+		Name:  "complex",
+		Julia: true,
+		New: func(params cliParams) Engine {
+			return NewComplexEngine(ComplexEngineParams{
+				EngineParams: params.engineParams(),
+			})
+		},
+	})
 }
 
 func NewComplexEngine(params ComplexEngineParams) *ComplexEngine {
-	engine := ComplexEngine{
-		width:         params.Width,
-		height:        params.Height,
-		fz:            Create4D[complex128](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1)),
-		fz2:           Create4D[complex128](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1)),
-		explodesAt:    Create4D[int](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1)),
-		maxExplodesAt: 1,
-		scale:         Elvis(params.Scale, 1),
-		scaleFactorX:  float64(3) / float64(params.Width*Elvis(params.Scale, 1)),
-		scaleFactorY:  (float64(3*params.Height) / float64(params.Width)) / float64(params.Width*Elvis(params.Scale, 1)),
-		centerX:       Elvis(params.CenterX, 0.75),
-		centerY:       Elvis(params.CenterY, 0),
-		subIterations: Elvis(params.SubIterations, 100),
-		iterations:    1,
-		chunkSizeX:    Elvis(params.ChunkSizeX, 1),
-		chunkSizeY:    Elvis(params.ChunkSizeY, 1),
-		image:         image.NewRGBA(image.Rect(0, 0, params.Width, params.Height)),
-	}
+	engine := ComplexEngine{engineCore: newEngineCore(params.EngineParams)}
+	engine.fz = newPixels[complex128](&engine.chunkGrid)
+	engine.fz2 = newPixels[complex128](&engine.chunkGrid)
+
+	engine.seedJulia(func(p int, px, py int32) {
+		re, im := engine.toPlane(px, py)
+		engine.fz[p] = complex(re, im)
+		engine.fz2[p] = complex(re*re, im*im)
+	})
 
 	return &engine
 }
 
 func (f *ComplexEngine) Perform(context context.Context, x, y int32) {
-	X := x * int32(f.chunkSizeX)
-	Y := y * int32(f.chunkSizeY)
+	escapeRadius2 := f.escapeRadius * f.escapeRadius
 
-	for _x := 0; _x < f.chunkSizeX; _x++ {
-		for _y := 0; _y < f.chunkSizeY; _y++ {
-			select {
-			case <-context.Done():
-				return
+	f.performChunk(context, x, y, func(p int, px, py int32) {
+		c := complex(f.parameter(f.toPlane(px, py)))
 
-			default:
-				XX := X + int32(_x)
-				YY := Y + int32(_y)
+		for i := range f.subIterations {
+			z1 := f.fz2[p]
 
-				if f.explodesAt[x][y][_x][_y] > 0 {
-					continue
-				}
-
-				dXX := float64(XX - int32(f.width/2))
-				dYY := float64(YY - int32(f.height/2))
-				_XX := f.centerX + dXX*f.scaleFactorX
-				_YY := f.centerY + dYY*f.scaleFactorY
-
-				for i := range f.subIterations {
-					z1 := f.fz2[x][y][_x][_y]
-
-					if real(z1)*real(z1)+imag(z1)*imag(z1) > 4 {
-						f.explodesAt[x][y][_x][_y] = f.iterations + i
-						if f.explodesAt[x][y][_x][_y] > f.maxExplodesAt {
-							f.maxExplodesAt = f.explodesAt[x][y][_x][_y]
-						}
-						break
-					}
-
-					z3 := f.fz[x][y][_x][_y]*f.fz[x][y][_x][_y] + complex(_XX, _YY)
-
-					f.fz[x][y][_x][_y] = z3
-
-					f.fz2[x][y][_x][_y] = complex(real(z3)*real(z3), imag(z3)*imag(z3))
-				}
+			if real(z1)+imag(z1) > escapeRadius2 {
+				f.escape(p, i, math.Sqrt(real(z1)+imag(z1)))
+				break
 			}
-		}
-	}
-}
 
-func (f *ComplexEngine) GetChunkedArea() int {
-	return (f.width / f.chunkSizeX) * (f.height / f.chunkSizeY)
-}
-
-func (f *ComplexEngine) GetExplodesAt(x, y int32) int {
-	xx := x / int32(f.chunkSizeX)
-	xy := x % int32(f.chunkSizeX)
-	yx := y / int32(f.chunkSizeY)
-	yy := y % int32(f.chunkSizeY)
-
-	ans := f.explodesAt[xx][yx][xy][yy]
-	return ans
-}
-
-func (f ComplexEngine) GetMaxExplodesAt() int {
-	return f.maxExplodesAt
-}
-
-func (f *ComplexEngine) IncreaseIteration() {
-	f.iterations += f.subIterations
-}
+			z3 := f.fz[p]*f.fz[p] + c
 
-func (f *ComplexEngine) GetIterations() int {
-	return f.iterations
-}
-
-func (f *ComplexEngine) ResetImage() {
-	f.image = image.NewRGBA(image.Rect(0, 0, f.width, f.height))
-}
-
-func (f *ComplexEngine) GetImage() *image.RGBA {
-	return f.image
-}
-
-func (f *ComplexEngine) IsStopped() bool {
-	return f.stopped
-}
+			f.fz[p] = z3
 
-func (f *ComplexEngine) Stop() {
-	f.stopped = true
+			f.fz2[p] = complex(real(z3)*real(z3), imag(z3)*imag(z3))
+		}
+	})
 }
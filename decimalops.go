@@ -1,6 +1,10 @@
 package main
 
-import "github.com/ericlagergren/decimal"
+import (
+	"strconv"
+
+	"github.com/ericlagergren/decimal"
+)
 
 func AddDecimal(a decimal.Big, b decimal.Big) decimal.Big {
 	var result decimal.Big
@@ -29,3 +33,43 @@ func Dec(a float64) decimal.Big {
 	result.SetFloat64(a)
 	return result
 }
+
+// ScaleDecimal multiplies the decimal string value by factor without
+// limiting it to the range of a float64, so zoom levels beyond it keep
+// working. Only the magnitude matters there, so the result keeps 16 digits.
+func ScaleDecimal(value string, factor float64) string {
+	var result decimal.Big
+	result.Context.Precision = 16
+	if _, ok := result.SetString(value); !ok {
+		return value
+	}
+
+	var multiplier decimal.Big
+	multiplier.SetString(strconv.FormatFloat(factor, 'g', -1, 64))
+
+	return result.Mul(&result, &multiplier).String()
+}
+
+// OffsetDecimal moves the decimal string value by delta / scale without
+// rounding either to a float64, so a deep zoom center keeps all of its digits
+// at scales beyond the range of a float64.
+func OffsetDecimal(value string, delta float64, scale string) string {
+	var result decimal.Big
+	result.Context.Precision = decimal.UnlimitedPrecision
+	if _, ok := result.SetString(value); !ok {
+		return value
+	}
+
+	var divisor decimal.Big
+	if _, ok := divisor.SetString(scale); !ok || !divisor.IsFinite() || divisor.Sign() <= 0 {
+		return value
+	}
+
+	// delta holds no more than the 17 digits of a float64.
+	var offset decimal.Big
+	offset.Context.Precision = 17
+	offset.SetString(strconv.FormatFloat(delta, 'g', -1, 64))
+	offset.Quo(&offset, &divisor)
+
+	return result.Add(&result, &offset).String()
+}
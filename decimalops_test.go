@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOffsetDecimal(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		delta float64
+		scale string
+		want  string
+	}{
+		{"-0.75", 0.5, "1", "-0.25"},
+		{"-0.75", 1.5, "1e3", "-0.7485"},
+		// Beyond the range of a float64, the offset is still there.
+		{"-0.75", 1.5, "1e400", "-0.74" + strings.Repeat("9", 397) + "85"},
+		// A scale that is no positive number leaves the value alone.
+		{"-0.75", 1.5, "Inf", "-0.75"},
+		{"-0.75", 1.5, "0", "-0.75"},
+	} {
+		if got := OffsetDecimal(test.value, test.delta, test.scale); got != test.want {
+			t.Errorf("OffsetDecimal(%s, %v, %s) = %s, want %s", test.value, test.delta, test.scale, got, test.want)
+		}
+	}
+}
+
+// TestViewportScale checks that the float64 viewport rejects the scales a
+// float64 cannot hold, which only the deep zoom engines render.
+func TestViewportScale(t *testing.T) {
+	params := testParams("fast", 64, 64)
+	for scale, valid := range map[string]bool{
+		"1":      true,
+		"1e300":  true,
+		"1e400":  false,
+		"1e-400": false,
+		"Inf":    false,
+		"NaN":    false,
+		"-1":     false,
+	} {
+		params.scale = scale
+		if _, err := params.viewport(); (err == nil) != valid {
+			t.Errorf("viewport at scale %s: error %v", scale, err)
+		}
+	}
+}
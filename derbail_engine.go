@@ -2,152 +2,137 @@ package main
 
 import (
 	"context"
-	"image"
+	"flag"
+	"math"
 	"math/cmplx"
 )
 
-type DerbailEngine struct {
-	zn            [][][][]complex128
-	zdashn        [][][][]complex128
-	zdashn_sum    [][][][]complex128
-	explodesAt    [][][][]int
-	image         *image.RGBA
-	maxExplodesAt int
-
-	width, height              int
-	scale                      int
-	scaleFactorX, scaleFactorY float64
+// distanceEscapeRadius is the least radius orbits escape at in distance
+// mode, as |z|·ln|z|/|z'| only estimates the distance well once |z| is large.
+const distanceEscapeRadius = 1e3
 
-	centerX, centerY float64
-
-	subIterations int
+type DerbailEngine struct {
+	*engineCore
 
-	chunkSizeX, chunkSizeY int
+	zn         []complex128
+	zdashn     []complex128
+	zdashn_sum []complex128
+	distance   []float64
 
-	iterations int
+	power float64
 
 	bailoutValue float64
 
-	stopped bool
+	// distanceEstimation escapes pixels on |z| > escapeRadius instead of the
+	// derivative bailout, where |z|·ln|z|/|z'| estimates their distance to
+	// the set.
+	distanceEstimation bool
 }
 
 type DerbailEngineParams struct {
-	Width, Height          int
-	CenterX, CenterY       *float64
-	Scale                  *int
-	SubIterations          *int
-	ChunkSizeX, ChunkSizeY *int
-	Bailout                *float64
+	EngineParams
+	Bailout            *float64
+	DistanceEstimation *bool
+	Power              *float64
+}
+
+func init() {
+	RegisterEngine(EngineDefinition{
+		Name: "derbail",
+		Flags: func(flags *flag.FlagSet, params *cliParams) {
+			flags.Float64Var(&params.bailout, "bailout", 1e4, "bailout value for derbail engine")
+		},
+		Julia:    true,
+		Powers:   true,
+		Distance: true,
+		New: func(params cliParams) Engine {
+			return NewDerbailEngine(DerbailEngineParams{
+				EngineParams:       params.engineParams(),
+				Bailout:            &params.bailout,
+				DistanceEstimation: Ptr(params.render == "distance"),
+				Power:              &params.power,
+			})
+		},
+	})
 }
 
 func NewDerbailEngine(params DerbailEngineParams) *DerbailEngine {
 	engine := DerbailEngine{
-		width:         params.Width,
-		height:        params.Height,
-		zn:            Create4D[complex128](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1)),
-		zdashn:        Create4DWithValue[complex128](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1), complex(1, 0)),
-		zdashn_sum:    Create4D[complex128](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1)),
-		explodesAt:    Create4D[int](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1)),
-		maxExplodesAt: 1,
-		scale:         Elvis(params.Scale, 1),
-		scaleFactorX:  float64(3) / float64(params.Width*Elvis(params.Scale, 1)),
-		scaleFactorY:  (float64(3*params.Height) / float64(params.Width)) / float64(params.Width*Elvis(params.Scale, 1)),
-		centerX:       Elvis(params.CenterX, 0.75),
-		centerY:       Elvis(params.CenterY, 0),
-		subIterations: Elvis(params.SubIterations, 100),
-		iterations:    1,
-		chunkSizeX:    Elvis(params.ChunkSizeX, 1),
-		chunkSizeY:    Elvis(params.ChunkSizeY, 1),
-		image:         image.NewRGBA(image.Rect(0, 0, params.Width, params.Height)),
-		bailoutValue:  Elvis(params.Bailout, 1e4),
+		engineCore:         newEngineCore(params.EngineParams),
+		bailoutValue:       Elvis(params.Bailout, 1e4),
+		distanceEstimation: Elvis(params.DistanceEstimation, false),
+		power:              Elvis(params.Power, 2),
+	}
+	if engine.distanceEstimation {
+		engine.escapeRadius = max(engine.escapeRadius, distanceEscapeRadius)
 	}
+	engine.zn = newPixels[complex128](&engine.chunkGrid)
+	engine.zdashn = newPixelsWithValue(&engine.chunkGrid, complex(1, 0))
+	engine.zdashn_sum = newPixels[complex128](&engine.chunkGrid)
+	engine.distance = newPixels[float64](&engine.chunkGrid)
+
+	// The derivative is taken with respect to z₀ in Julia mode, so z'₀ stays
+	// at 1.
+	engine.seedJulia(func(p int, px, py int32) {
+		engine.zn[p] = complex(engine.toPlane(px, py))
+	})
 
 	return &engine
 }
 
 func (f *DerbailEngine) Perform(context context.Context, x, y int32) {
-	X := x * int32(f.chunkSizeX)
-	Y := y * int32(f.chunkSizeY)
-
-	for _x := 0; _x < f.chunkSizeX; _x++ {
-		for _y := 0; _y < f.chunkSizeY; _y++ {
-			select {
-			case <-context.Done():
-				return
-
-			default:
-				XX := X + int32(_x)
-				YY := Y + int32(_y)
+	escapeRadius2 := f.escapeRadius * f.escapeRadius
 
-				if f.explodesAt[x][y][_x][_y] > 0 {
-					continue
-				}
+	f.performChunk(context, x, y, func(p int, px, py int32) {
+		c := complex(f.parameter(f.toPlane(px, py)))
 
-				dXX := float64(XX - int32(f.width/2))
-				dYY := float64(YY - int32(f.height/2))
-				_XX := f.centerX + dXX*f.scaleFactorX
-				_YY := f.centerY + dYY*f.scaleFactorY
-
-				for i := range f.subIterations {
-					new_zdash := (complex(2, 0) * f.zdashn[x][y][_x][_y] * f.zn[x][y][_x][_y]) + complex(1, 0)
-					new_zn := cmplx.Pow(f.zn[x][y][_x][_y], 2) + complex(_XX, _YY)
-					new_zdashsum := f.zdashn_sum[x][y][_x][_y] + new_zdash
-
-					if real(new_zdashsum)*real(new_zdashsum)+imag(new_zdashsum)*imag(new_zdashsum) > f.bailoutValue {
-						f.explodesAt[x][y][_x][_y] = f.iterations + i
-						if f.explodesAt[x][y][_x][_y] > f.maxExplodesAt {
-							f.maxExplodesAt = f.explodesAt[x][y][_x][_y]
-						}
-						break
-					}
-
-					f.zdashn[x][y][_x][_y] = new_zdash
-					f.zn[x][y][_x][_y] = new_zn
-					f.zdashn_sum[x][y][_x][_y] = new_zdashsum
+		for i := range f.subIterations {
+			// z' = d·z^(d-1)·z' + 1, where the + 1 is dc/dc.
+			new_zdash := complex(f.power, 0) * f.zdashn[p] * pow(f.zn[p], f.power-1)
+			if !f.julia {
+				new_zdash += complex(1, 0)
+			}
+			new_zn := pow(f.zn[p], f.power) + c
+			new_zdashsum := f.zdashn_sum[p] + new_zdash
+
+			if f.distanceEstimation {
+				if real(new_zn)*real(new_zn)+imag(new_zn)*imag(new_zn) > escapeRadius2 {
+					modulus := cmplx.Abs(new_zn)
+					f.escape(p, i, modulus)
+					f.distance[p] = modulus * math.Log(modulus) / cmplx.Abs(new_zdash)
+					break
 				}
+			} else if real(new_zdashsum)*real(new_zdashsum)+imag(new_zdashsum)*imag(new_zdashsum) > f.bailoutValue {
+				f.escape(p, i, cmplx.Abs(new_zdashsum))
+				break
 			}
-		}
-	}
-}
-
-func (f *DerbailEngine) GetChunkedArea() int {
-	return (f.width / f.chunkSizeX) * (f.height / f.chunkSizeY)
-}
-
-func (f *DerbailEngine) GetExplodesAt(x, y int32) int {
-	xx := x / int32(f.chunkSizeX)
-	xy := x % int32(f.chunkSizeX)
-	yx := y / int32(f.chunkSizeY)
-	yy := y % int32(f.chunkSizeY)
-
-	ans := f.explodesAt[xx][yx][xy][yy]
-	return ans
-}
 
-func (f DerbailEngine) GetMaxExplodesAt() int {
-	return f.maxExplodesAt
-}
-
-func (f *DerbailEngine) IncreaseIteration() {
-	f.iterations += f.subIterations
+			f.zdashn[p] = new_zdash
+			f.zn[p] = new_zn
+			f.zdashn_sum[p] = new_zdashsum
+		}
+	})
 }
 
-func (f *DerbailEngine) GetIterations() int {
-	return f.iterations
-}
+// GetSmoothExplodesAt normalizes by the derivative sum that triggered the
+// bailout, which plays the role of |z| against a radius of √bailout.
+func (f *DerbailEngine) GetSmoothExplodesAt(x, y int32) float64 {
+	p := f.pixelIndex(x, y)
 
-func (f *DerbailEngine) ResetImage() {
-	f.image = image.NewRGBA(image.Rect(0, 0, f.width, f.height))
+	radius := math.Sqrt(f.bailoutValue)
+	if f.distanceEstimation {
+		radius = f.escapeRadius
+	}
+	return smoothIterationOfDegree(f.explodesAt[p], f.escapeModulus[p], radius, f.power)
 }
 
-func (f *DerbailEngine) GetImage() *image.RGBA {
-	return f.image
-}
+func (f *DerbailEngine) GetDistance(x, y int32) float64 {
+	p := f.pixelIndex(x, y)
 
-func (f *DerbailEngine) IsStopped() bool {
-	return f.stopped
+	return f.distance[p]
 }
 
-func (f *DerbailEngine) Stop() {
-	f.stopped = true
+func (f *DerbailEngine) GetPixelSize() float64 {
+	scaleFactorX, _ := f.viewport.ScaleFactors()
+	return scaleFactorX
 }
@@ -3,11 +3,20 @@ package main
 import (
 	"context"
 	"image"
+	"image/color"
 )
 
+// Engine iterates the pixels of the image chunk by chunk.
+//
+// Perform runs concurrently for different chunks, never twice at once for
+// the same one. The getters of a pixel may run concurrently with Perform on
+// other chunks, GetMaxExplodesAt and IsStopped with Perform on any chunk,
+// and Stop at any time. IncreaseIteration, ResetImage and FinishIteration
+// only run between rounds of Perform.
 type Engine interface {
 	Perform(context context.Context, x, y int32)
 	GetExplodesAt(x, y int32) int
+	GetSmoothExplodesAt(x, y int32) float64
 	GetMaxExplodesAt() int
 	ResetImage()
 	IncreaseIteration()
@@ -19,3 +28,23 @@ type Engine interface {
 	IsStopped() bool
 	Stop()
 }
+
+// DistanceEstimator is implemented by engines estimating how far escaped
+// pixels lie from the set, in units of the complex plane.
+type DistanceEstimator interface {
+	GetDistance(x, y int32) float64
+	GetPixelSize() float64
+}
+
+// ColoringEngine is implemented by engines whose pixels carry more than an
+// iteration count, so they pick their colors from the palette themselves.
+type ColoringEngine interface {
+	GetColor(x, y int32, colorPicker ColorOf) color.RGBA
+}
+
+// IterationFinisher is implemented by engines gathering what the chunks of an
+// iteration left behind once all of them are done, before the image is
+// painted as a whole.
+type IterationFinisher interface {
+	FinishIteration()
+}
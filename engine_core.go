@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"image"
+	"sync/atomic"
+)
+
+// EngineParams are the parameters every engine iterating in float64 takes.
+type EngineParams struct {
+	Width, Height          int
+	CenterX, CenterY       *float64
+	Scale                  *float64
+	Angle                  *float64
+	SubIterations          *int
+	ChunkSizeX, ChunkSizeY *int
+	Julia                  *bool
+	CReal, CImag           *float64
+	EscapeRadius           *float64
+}
+
+// engineCore is the part of an engine that does not depend on what it
+// iterates: the image and its chunk grid, the viewport, when every pixel
+// escaped and whether the engine was stopped. Engines embed it and add their
+// per-pixel state and the iteration kernel run by performChunk. Engines whose
+// pixels do not escape, like the Lyapunov and the Buddhabrot, embed it for
+// the rest and bring their own getters.
+//
+// Perform runs concurrently for different chunks, which only share
+// maxExplodesAt and stopped, both updated atomically. The state of a pixel
+// and whether its chunk is excluded belong to the chunk's Perform call.
+type engineCore struct {
+	chunkGrid
+
+	explodesAt    []int
+	escapeModulus []float64
+	excluded      []bool
+	image         *image.RGBA
+	maxExplodesAt atomic.Int64
+
+	viewport Viewport
+
+	julia  bool
+	cr, ci float64
+
+	escapeRadius float64
+
+	subIterations int
+
+	iterations int
+
+	stopped atomic.Bool
+}
+
+// newEngineCore returns the core of an engine whose pixels escape, with room
+// for when and how far out every pixel escaped.
+func newEngineCore(params EngineParams) *engineCore {
+	core := newEngineCoreWithoutEscapes(params)
+	core.explodesAt = newPixels[int](&core.chunkGrid)
+	core.escapeModulus = newPixels[float64](&core.chunkGrid)
+	return core
+}
+
+// newEngineCoreWithoutEscapes leaves out the per-pixel escape state, for
+// engines like the Buddhabrot that keep their own and bring their getters.
+func newEngineCoreWithoutEscapes(params EngineParams) *engineCore {
+	core := engineCore{
+		chunkGrid:     newChunkGrid(params.Width, params.Height, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1)),
+		escapeRadius:  Elvis(params.EscapeRadius, 2),
+		viewport:      NewViewport(params.Width, params.Height, Elvis(params.CenterX, 0.75), Elvis(params.CenterY, 0), Elvis(params.Scale, 1), Elvis(params.Angle, 0)),
+		subIterations: Elvis(params.SubIterations, 100),
+		iterations:    1,
+		image:         image.NewRGBA(image.Rect(0, 0, params.Width, params.Height)),
+		julia:         Elvis(params.Julia, false),
+		cr:            Elvis(params.CReal, 0),
+		ci:            Elvis(params.CImag, 0),
+	}
+	core.excluded = make([]bool, core.GetChunkedArea())
+	core.maxExplodesAt.Store(1)
+
+	return &core
+}
+
+// chunkGrid splits the image into chunks and lays out per-pixel state in flat
+// slices, chunk after chunk and row after row within a chunk, so the pixels a
+// Perform call visits lie next to each other in memory.
+type chunkGrid struct {
+	width, height          int
+	chunkSizeX, chunkSizeY int
+	chunksX, chunksY       int
+}
+
+func newChunkGrid(width, height, chunkSizeX, chunkSizeY int) chunkGrid {
+	return chunkGrid{
+		width:      width,
+		height:     height,
+		chunkSizeX: chunkSizeX,
+		chunkSizeY: chunkSizeY,
+		chunksX:    width / chunkSizeX,
+		chunksY:    height / chunkSizeY,
+	}
+}
+
+// newPixels allocates per-pixel state, indexed by chunkGrid.index.
+func newPixels[T any](grid *chunkGrid) []T {
+	return make([]T, grid.chunksX*grid.chunksY*grid.chunkSizeX*grid.chunkSizeY)
+}
+
+func newPixelsWithValue[T any](grid *chunkGrid, value T) []T {
+	pixels := newPixels[T](grid)
+	for i := range pixels {
+		pixels[i] = value
+	}
+	return pixels
+}
+
+// index returns where pixel (_x, _y) of chunk (x, y) lies in per-pixel state.
+func (g *chunkGrid) index(x, y, _x, _y int32) int {
+	return ((int(y)*g.chunksX+int(x))*g.chunkSizeY+int(_y))*g.chunkSizeX + int(_x)
+}
+
+// pixelIndex returns where pixel (x, y) of the image lies in per-pixel state.
+func (g *chunkGrid) pixelIndex(x, y int32) int {
+	return g.index(x/int32(g.chunkSizeX), y/int32(g.chunkSizeY), x%int32(g.chunkSizeX), y%int32(g.chunkSizeY))
+}
+
+func (g *chunkGrid) chunkIndex(x, y int32) int {
+	return int(y)*g.chunksX + int(x)
+}
+
+// forEachPixel calls visit with the index and the coordinates of every pixel.
+func (g *chunkGrid) forEachPixel(visit func(p int, px, py int32)) {
+	p := 0
+	for y := range int32(g.chunksY) {
+		for x := range int32(g.chunksX) {
+			X := x * int32(g.chunkSizeX)
+			Y := y * int32(g.chunkSizeY)
+
+			for py := Y; py < Y+int32(g.chunkSizeY); py++ {
+				for px := X; px < X+int32(g.chunkSizeX); px, p = px+1, p+1 {
+					visit(p, px, py)
+				}
+			}
+		}
+	}
+}
+
+func (g *chunkGrid) GetChunkedArea() int {
+	return g.chunksX * g.chunksY
+}
+
+// toPlane returns the point of the plane under pixel (px, py).
+func (f *engineCore) toPlane(px, py int32) (float64, float64) {
+	return f.viewport.ToPlane(float64(px), float64(py))
+}
+
+// parameter returns the c a pixel at (re, im) iterates with, the pixel
+// itself unless in Julia mode.
+func (f *engineCore) parameter(re, im float64) (float64, float64) {
+	if f.julia {
+		return f.cr, f.ci
+	}
+	return re, im
+}
+
+// seedJulia starts every pixel's orbit at the pixel in Julia mode, where z₀
+// is the pixel and c is fixed, by calling seed for each. It does nothing in
+// Mandelbrot mode, where orbits start at zero.
+func (f *engineCore) seedJulia(seed func(p int, px, py int32)) {
+	if f.julia {
+		f.forEachPixel(seed)
+	}
+}
+
+// performChunk runs kernel on every pixel of chunk (x, y) that has not
+// escaped yet, and excludes the chunk from later iterations once none is
+// left. The kernel maps the pixel to the plane itself, engines working
+// beyond float64 precision do so differently. The largest explodesAt the
+// kernel sets raises maxExplodesAt once the chunk is done.
+func (f *engineCore) performChunk(context context.Context, x, y int32, kernel func(p int, px, py int32)) {
+	X := x * int32(f.chunkSizeX)
+	Y := y * int32(f.chunkSizeY)
+
+	chunkMax := 0
+	defer func() {
+		f.raiseMaxExplodesAt(chunkMax)
+	}()
+
+	performCount := 0
+	p := f.index(x, y, 0, 0)
+	for py := Y; py < Y+int32(f.chunkSizeY); py++ {
+		for px := X; px < X+int32(f.chunkSizeX); px, p = px+1, p+1 {
+			select {
+			case <-context.Done():
+				return
+
+			default:
+				if f.explodesAt[p] != 0 {
+					continue
+				}
+				performCount++
+
+				kernel(p, px, py)
+				chunkMax = max(chunkMax, f.explodesAt[p])
+			}
+		}
+	}
+
+	if performCount == 0 {
+		f.excluded[f.chunkIndex(x, y)] = true
+	}
+}
+
+// escape records that the pixel escaped at sub-iteration i of the current
+// iteration with |z| = modulus.
+func (f *engineCore) escape(p int, i int, modulus float64) {
+	f.explodesAt[p] = f.iterations + i
+	f.escapeModulus[p] = modulus
+}
+
+// raiseMaxExplodesAt makes maxExplodesAt at least value.
+func (f *engineCore) raiseMaxExplodesAt(value int) {
+	for {
+		current := f.maxExplodesAt.Load()
+		if int64(value) <= current || f.maxExplodesAt.CompareAndSwap(current, int64(value)) {
+			return
+		}
+	}
+}
+
+func (f *engineCore) CanSkipChunk(x, y int32) bool {
+	return f.excluded[f.chunkIndex(x, y)]
+}
+
+func (f *engineCore) GetExplodesAt(x, y int32) int {
+	return f.explodesAt[f.pixelIndex(x, y)]
+}
+
+func (f *engineCore) GetSmoothExplodesAt(x, y int32) float64 {
+	p := f.pixelIndex(x, y)
+	return smoothIteration(f.explodesAt[p], f.escapeModulus[p], f.escapeRadius)
+}
+
+func (f *engineCore) GetMaxExplodesAt() int {
+	return int(f.maxExplodesAt.Load())
+}
+
+func (f *engineCore) IncreaseIteration() {
+	f.iterations += f.subIterations
+}
+
+func (f *engineCore) GetIterations() int {
+	return f.iterations
+}
+
+func (f *engineCore) ResetImage() {
+	f.image = image.NewRGBA(image.Rect(0, 0, f.width, f.height))
+}
+
+func (f *engineCore) GetImage() *image.RGBA {
+	return f.image
+}
+
+func (f *engineCore) IsStopped() bool {
+	return f.stopped.Load()
+}
+
+func (f *engineCore) Stop() {
+	f.stopped.Store(true)
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestChunkGridIndex(t *testing.T) {
+	grid := newChunkGrid(12, 8, 4, 2)
+	area := grid.chunkSizeX * grid.chunkSizeY
+
+	seen := make([]bool, len(newPixels[int](&grid)))
+	for y := range int32(grid.height) {
+		for x := range int32(grid.width) {
+			p := grid.pixelIndex(x, y)
+			if seen[p] {
+				t.Fatalf("pixel (%d, %d) shares index %d with another pixel", x, y, p)
+			}
+			seen[p] = true
+
+			// The pixels of a chunk are stored next to each other.
+			chunk := grid.chunkIndex(x/int32(grid.chunkSizeX), y/int32(grid.chunkSizeY))
+			if p/area != chunk {
+				t.Errorf("pixel (%d, %d) at %d lies outside chunk %d", x, y, p, chunk)
+			}
+		}
+	}
+}
+
+func TestChunkGridForEachPixel(t *testing.T) {
+	grid := newChunkGrid(12, 8, 4, 2)
+
+	next := 0
+	grid.forEachPixel(func(p int, px, py int32) {
+		if p != next {
+			t.Fatalf("visited index %d, want %d", p, next)
+		}
+		if want := grid.pixelIndex(px, py); p != want {
+			t.Errorf("pixel (%d, %d) visited as %d, want %d", px, py, p, want)
+		}
+		next++
+	})
+	if next != grid.width*grid.height {
+		t.Errorf("visited %d pixels, want %d", next, grid.width*grid.height)
+	}
+}
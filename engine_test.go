@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testParams returns the parameters main hands to the engine called name for
+// a small image, with the defaults of its flags.
+func testParams(name string, width, height int) cliParams {
+	params := cliParams{
+		width:         width,
+		height:        height,
+		chunkSizeX:    8,
+		chunkSizeY:    8,
+		centerX:       "-0.75",
+		centerY:       "0",
+		scale:         "1",
+		subiterations: 50,
+		escapeRadius:  2,
+		render:        "iterations",
+		sampler:       "linear",
+		engine:        name,
+		formula:       "mandelbrot",
+		power:         2,
+		cr:            -0.7,
+		ci:            0.27015,
+	}
+
+	// Registering the flags of the engines sets their defaults.
+	flags := flag.NewFlagSet(name, flag.PanicOnError)
+	for _, definition := range engineDefinitions() {
+		if definition.Flags != nil {
+			definition.Flags(flags, &params)
+		}
+	}
+
+	if definition, _ := LookupEngine(name); definition.View != nil {
+		params.centerX, params.centerY, params.scale = definition.View.CenterX, definition.View.CenterY, definition.View.Scale
+	}
+	return params
+}
+
+// painter returns what main paints the pixels of engine with.
+func painter(params cliParams, engine Engine) func(px, py int) {
+	converter := ExponentialMappedModuloColorRangeConverer{S: 1.1, Steps: 20}
+	update := imageUpdaterOf(params, engine)
+
+	return func(px, py int) {
+		update(engine.GetImage(), px, py, converter, SpectralColor{}, engine)
+	}
+}
+
+// render performs iterations of the engine params ask for and paints it
+// the way main does, chunk by chunk and then as a whole.
+func render(params cliParams, iterations int) Engine {
+	definition, _ := LookupEngine(params.engine)
+	engine := definition.New(params)
+	sampler := newSampler(params.sampler, params.height/params.chunkSizeY, params.width/params.chunkSizeX)
+	paint := painter(params, engine)
+
+	for range iterations {
+		engine.IncreaseIteration()
+		performIteration(context.Background(), engine, sampler, params.chunkSizeX, params.chunkSizeY, paint)
+
+		for py := range params.height {
+			for px := range params.width {
+				paint(px, py)
+			}
+		}
+	}
+	return engine
+}
+
+// TestEnginesPerformConcurrently runs every engine the way main does, for go
+// test -race. Painting reads the pixels and the largest iteration count while
+// other chunks are being performed.
+func TestEnginesPerformConcurrently(t *testing.T) {
+	for _, definition := range engineDefinitions() {
+		t.Run(definition.Name, func(t *testing.T) {
+			params := testParams(definition.Name, 64, 32)
+			engine := definition.New(params)
+			sampler := newSampler("hilbert", params.height/params.chunkSizeY, params.width/params.chunkSizeX)
+
+			for range 2 {
+				engine.IncreaseIteration()
+				performIteration(context.Background(), engine, sampler, params.chunkSizeX, params.chunkSizeY, painter(params, engine))
+			}
+
+			// Stopping halfway through an iteration leaves it early.
+			engine.IncreaseIteration()
+			var stopper sync.WaitGroup
+			stopper.Add(1)
+			go func() {
+				defer stopper.Done()
+				engine.Stop()
+			}()
+			performIteration(context.Background(), engine, sampler, params.chunkSizeX, params.chunkSizeY, painter(params, engine))
+			stopper.Wait()
+
+			if !engine.IsStopped() {
+				t.Error("engine not stopped")
+			}
+		})
+	}
+}
+
+// TestEngineFlagsHaveOneOwner checks that every engine-only flag belongs to
+// a single engine, which verify names when the flag is given to another one.
+func TestEngineFlagsHaveOneOwner(t *testing.T) {
+	owners := map[string]string{}
+	for _, definition := range engineDefinitions() {
+		for _, name := range definition.flagNames() {
+			if owner, ok := owners[name]; ok {
+				t.Errorf("-%s belongs to both the %s and the %s engine", name, owner, definition.Name)
+			}
+			owners[name] = definition.Name
+		}
+	}
+
+	for name, owner := range map[string]string{"sa": "perturbation", "phoenix": "fast", "bailout": "derbail", "poly": "newton"} {
+		if owners[name] != owner {
+			t.Errorf("-%s belongs to the %q engine, want %s", name, owners[name], owner)
+		}
+	}
+}
+
+// TestNonSquareImage renders a wide image, which must match the middle rows
+// of a square one of the same width, whatever order the chunks come in.
+func TestNonSquareImage(t *testing.T) {
+	renderWith := func(sampler string, width, height int) Engine {
+		params := testParams("fast", width, height)
+		params.sampler = sampler
+		return render(params, 1)
+	}
+
+	for _, sampler := range []string{"linear", "hilbert", "cachedhilbert"} {
+		t.Run(sampler, func(t *testing.T) {
+			square := renderWith(sampler, 64, 64)
+			wide := renderWith(sampler, 64, 32)
+
+			for y := range int32(32) {
+				for x := range int32(64) {
+					if got, want := wide.GetExplodesAt(x, y), square.GetExplodesAt(x, y+16); got != want {
+						t.Fatalf("pixel (%d, %d) escaped at %d, want %d", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden images in testdata")
+
+// goldenTolerance is the share of pixels allowed to differ from a golden
+// image, as architectures fusing multiply-adds round a few orbits apart.
+const goldenTolerance = 0.01
+
+var goldenCases = []struct {
+	name  string
+	setup func(params *cliParams)
+}{
+	{"fast", nil},
+	{"fast_julia", func(params *cliParams) {
+		params.julia = true
+		params.centerX, params.centerY = "0", "0"
+	}},
+	{"fast_burningship_smooth", func(params *cliParams) {
+		params.formula = "burningship"
+		params.smooth = true
+		view := BurningShip.DefaultView()
+		params.centerX, params.centerY, params.scale = view.CenterX, view.CenterY, view.Scale
+	}},
+	{"fast_power3", func(params *cliParams) {
+		params.power = 3
+		params.centerX = "0"
+	}},
+	{"complex", nil},
+	{"derbail", nil},
+	{"derbail_distance", func(params *cliParams) {
+		params.render = "distance"
+	}},
+	{"expr", func(params *cliParams) {
+		params.expr = "conj(z)^2 + c"
+	}},
+	{"newton", func(params *cliParams) {
+		params.centerX = "0"
+	}},
+	{"arbitrary", nil},
+	{"perturbation", func(params *cliParams) {
+		params.centerX, params.centerY, params.scale = "-0.7436", "0.1318", "1e4"
+		params.subiterations = 200
+	}},
+	{"buddhabrot", func(params *cliParams) {
+		params.centerX = "-0.5"
+	}},
+	{"lyapunov", nil},
+}
+
+// TestGoldenImages renders every engine small and compares the image with
+// the one in testdata/golden, which go test -update rewrites.
+func TestGoldenImages(t *testing.T) {
+	for _, golden := range goldenCases {
+		t.Run(golden.name, func(t *testing.T) {
+			engine, _, _ := strings.Cut(golden.name, "_")
+			params := testParams(engine, 64, 64)
+			params.chunkSizeX, params.chunkSizeY = 16, 16
+			if golden.setup != nil {
+				golden.setup(&params)
+			}
+			got := render(params, 3).GetImage()
+
+			path := filepath.Join("testdata", "golden", golden.name+".png")
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := savePNG(path, got, nil); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := loadPNG(path)
+			if err != nil {
+				t.Fatalf("%v, run go test -update to create it", err)
+			}
+			if got.Bounds() != want.Bounds() {
+				t.Fatalf("image is %v, golden image %v", got.Bounds(), want.Bounds())
+			}
+
+			differ := 0
+			for y := range got.Bounds().Dy() {
+				for x := range got.Bounds().Dx() {
+					r1, g1, b1, a1 := got.At(x, y).RGBA()
+					r2, g2, b2, a2 := want.At(x, y).RGBA()
+					if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+						differ++
+					}
+				}
+			}
+			if total := got.Bounds().Dx() * got.Bounds().Dy(); float64(differ) > goldenTolerance*float64(total) {
+				t.Errorf("%d of %d pixels differ from %s", differ, total, path)
+			}
+		})
+	}
+}
+
+func loadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
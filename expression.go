@@ -0,0 +1,464 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"unicode"
+)
+
+// maxExpressionStack bounds how many values an expression may keep on the
+// stack at once, that is how deeply its operands may nest.
+const maxExpressionStack = 64
+
+type opcode uint8
+
+const (
+	opConst opcode = iota
+	opZ
+	opC
+	opPixel
+	opAdd
+	opSub
+	opMul
+	opDiv
+	opPow
+	// opPowConst raises to the real constant at arg, the common z^2 case.
+	opPowConst
+	opNeg
+	opCall
+)
+
+type instruction struct {
+	op  opcode
+	arg int
+}
+
+var expressionFunctions = map[string]func(complex128) complex128{
+	"abs":  func(z complex128) complex128 { return complex(cmplx.Abs(z), 0) },
+	"re":   func(z complex128) complex128 { return complex(real(z), 0) },
+	"im":   func(z complex128) complex128 { return complex(imag(z), 0) },
+	"conj": cmplx.Conj,
+	"sqrt": cmplx.Sqrt,
+	"exp":  cmplx.Exp,
+	"log":  cmplx.Log,
+	"sin":  cmplx.Sin,
+	"cos":  cmplx.Cos,
+	"tan":  cmplx.Tan,
+	"sinh": cmplx.Sinh,
+	"cosh": cmplx.Cosh,
+	"tanh": cmplx.Tanh,
+}
+
+var expressionConstants = map[string]complex128{
+	"i":  complex(0, 1),
+	"pi": complex(math.Pi, 0),
+	"e":  complex(math.E, 0),
+}
+
+// Expression is a formula in z, c and pixel compiled into instructions for
+// a small stack machine.
+type Expression struct {
+	code      []instruction
+	constants []complex128
+	functions []func(complex128) complex128
+	// stackSize is the most values the code keeps on the stack at once.
+	stackSize int
+}
+
+// CompileExpression parses text such as "z^2 + c" or "sin(z) * c". It knows
+// the variables z, c and pixel, the constants i, pi and e, the operators
+// + - * / ^ and the functions abs, re, im, conj, sqrt, exp, log, sin, cos,
+// tan, sinh, cosh and tanh. A number followed by a variable, a function or a
+// parenthesis multiplies it, as in 2z or 3(z+1).
+func CompileExpression(text string) (*Expression, error) {
+	p := expressionParser{text: text, expression: &Expression{}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if err := p.parseSum(); err != nil {
+		return nil, err
+	}
+	if p.token.kind != tokenEnd {
+		return nil, p.errorf("unexpected %q", p.token.text)
+	}
+	return p.expression, nil
+}
+
+// NewStack allocates the stack Eval runs on, which callers reuse across
+// calls from one goroutine.
+func (e *Expression) NewStack() []complex128 {
+	return make([]complex128, e.stackSize)
+}
+
+// Eval runs the expression for one step of an orbit on a stack from
+// NewStack.
+func (e *Expression) Eval(stack []complex128, z, c, pixel complex128) complex128 {
+	sp := 0
+
+	for _, in := range e.code {
+		switch in.op {
+		case opConst:
+			stack[sp] = e.constants[in.arg]
+			sp++
+		case opZ:
+			stack[sp] = z
+			sp++
+		case opC:
+			stack[sp] = c
+			sp++
+		case opPixel:
+			stack[sp] = pixel
+			sp++
+		case opAdd:
+			sp--
+			stack[sp-1] += stack[sp]
+		case opSub:
+			sp--
+			stack[sp-1] -= stack[sp]
+		case opMul:
+			sp--
+			stack[sp-1] *= stack[sp]
+		case opDiv:
+			sp--
+			stack[sp-1] /= stack[sp]
+		case opPow:
+			sp--
+			stack[sp-1] = cmplx.Pow(stack[sp-1], stack[sp])
+		case opPowConst:
+			stack[sp-1] = pow(stack[sp-1], real(e.constants[in.arg]))
+		case opNeg:
+			stack[sp-1] = -stack[sp-1]
+		case opCall:
+			stack[sp-1] = e.functions[in.arg](stack[sp-1])
+		}
+	}
+
+	return stack[0]
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenNumber
+	tokenName
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value complex128
+	at    int
+}
+
+type expressionParser struct {
+	text       string
+	at         int
+	token      token
+	expression *Expression
+	depth      int
+}
+
+func (p *expressionParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s at column %d of %q", fmt.Sprintf(format, args...), p.token.at+1, p.text)
+}
+
+// next reads the following token into p.token.
+func (p *expressionParser) next() error {
+	for p.at < len(p.text) && unicode.IsSpace(rune(p.text[p.at])) {
+		p.at++
+	}
+	start := p.at
+	if p.at == len(p.text) {
+		p.token = token{kind: tokenEnd, text: "end", at: start}
+		return nil
+	}
+
+	ch := p.text[p.at]
+	switch {
+	case isDigit(ch) || ch == '.':
+		for p.at < len(p.text) && (isDigit(p.text[p.at]) || p.text[p.at] == '.') {
+			p.at++
+		}
+		// Only take an e followed by digits as an exponent, 2exp(z) is a
+		// product.
+		if exponent := p.at + 1; exponent < len(p.text) && (p.text[p.at] == 'e' || p.text[p.at] == 'E') {
+			if (p.text[exponent] == '+' || p.text[exponent] == '-') && exponent+1 < len(p.text) {
+				exponent++
+			}
+			if isDigit(p.text[exponent]) {
+				p.at = exponent
+				for p.at < len(p.text) && isDigit(p.text[p.at]) {
+					p.at++
+				}
+			}
+		}
+		value, err := strconv.ParseFloat(p.text[start:p.at], 64)
+		if err != nil {
+			p.token.at = start
+			return p.errorf("invalid number %q", p.text[start:p.at])
+		}
+		p.token = token{kind: tokenNumber, text: p.text[start:p.at], value: complex(value, 0), at: start}
+
+		// 2i is an imaginary number, 2in is not.
+		if p.at < len(p.text) && p.text[p.at] == 'i' && (p.at+1 == len(p.text) || !isNameByte(p.text[p.at+1])) {
+			p.at++
+			p.token.text = p.text[start:p.at]
+			p.token.value = complex(0, value)
+		}
+
+	case isNameByte(ch):
+		for p.at < len(p.text) && isNameByte(p.text[p.at]) {
+			p.at++
+		}
+		p.token = token{kind: tokenName, text: p.text[start:p.at], at: start}
+
+	case ch == '+' || ch == '-' || ch == '*' || ch == '/' || ch == '^' || ch == '(' || ch == ')':
+		p.at++
+		p.token = token{kind: tokenOperator, text: string(ch), at: start}
+
+	default:
+		p.token.at = start
+		return p.errorf("unexpected %q", string(ch))
+	}
+	return nil
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isNameByte(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_'
+}
+
+func (p *expressionParser) isOperator(text string) bool {
+	return p.token.kind == tokenOperator && p.token.text == text
+}
+
+// emit appends an instruction, evaluating it right away when its operands
+// are constants.
+func (p *expressionParser) emit(in instruction) error {
+	e := p.expression
+	code := e.code
+	n := len(code)
+
+	switch in.op {
+	case opConst, opZ, opC, opPixel:
+		p.depth++
+		if p.depth > maxExpressionStack {
+			return p.errorf("expression nests too deeply")
+		}
+		e.stackSize = max(e.stackSize, p.depth)
+
+	case opAdd, opSub, opMul, opDiv, opPow:
+		p.depth--
+		if n >= 2 && code[n-2].op == opConst && code[n-1].op == opConst {
+			a, b := e.constants[code[n-2].arg], e.constants[code[n-1].arg]
+			e.code = code[:n-2]
+			p.depth--
+			return p.emitConstant(applyBinary(in.op, a, b))
+		}
+		if in.op == opPow && code[n-1].op == opConst && imag(e.constants[code[n-1].arg]) == 0 {
+			in = instruction{op: opPowConst, arg: code[n-1].arg}
+			e.code = code[:n-1]
+		}
+
+	case opNeg, opCall:
+		if code[n-1].op == opConst {
+			a := e.constants[code[n-1].arg]
+			e.code = code[:n-1]
+			p.depth--
+			if in.op == opNeg {
+				return p.emitConstant(-a)
+			}
+			return p.emitConstant(e.functions[in.arg](a))
+		}
+	}
+
+	e.code = append(e.code, in)
+	return nil
+}
+
+func (p *expressionParser) emitConstant(value complex128) error {
+	p.expression.constants = append(p.expression.constants, value)
+	return p.emit(instruction{op: opConst, arg: len(p.expression.constants) - 1})
+}
+
+func applyBinary(op opcode, a, b complex128) complex128 {
+	switch op {
+	case opAdd:
+		return a + b
+	case opSub:
+		return a - b
+	case opMul:
+		return a * b
+	case opDiv:
+		return a / b
+	}
+	return cmplx.Pow(a, b)
+}
+
+// parseSum parses terms joined by + and -.
+func (p *expressionParser) parseSum() error {
+	if err := p.parseProduct(); err != nil {
+		return err
+	}
+	for p.isOperator("+") || p.isOperator("-") {
+		op := opAdd
+		if p.token.text == "-" {
+			op = opSub
+		}
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.parseProduct(); err != nil {
+			return err
+		}
+		if err := p.emit(instruction{op: op}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseProduct parses factors joined by * and /, or written next to each
+// other after a number.
+func (p *expressionParser) parseProduct() error {
+	startsWithNumber := p.token.kind == tokenNumber
+	if err := p.parseUnary(); err != nil {
+		return err
+	}
+
+	for {
+		op := opMul
+		switch {
+		case p.isOperator("*"):
+		case p.isOperator("/"):
+			op = opDiv
+		case startsWithNumber && (p.token.kind == tokenName || p.isOperator("(")):
+			// Implicit multiplication, as in 2z.
+			startsWithNumber = false
+			if err := p.parseUnary(); err != nil {
+				return err
+			}
+			if err := p.emit(instruction{op: opMul}); err != nil {
+				return err
+			}
+			continue
+		default:
+			return nil
+		}
+
+		if err := p.next(); err != nil {
+			return err
+		}
+		startsWithNumber = p.token.kind == tokenNumber
+		if err := p.parseUnary(); err != nil {
+			return err
+		}
+		if err := p.emit(instruction{op: op}); err != nil {
+			return err
+		}
+	}
+}
+
+// parseUnary parses a signed power.
+func (p *expressionParser) parseUnary() error {
+	if p.isOperator("-") || p.isOperator("+") {
+		negate := p.token.text == "-"
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.parseUnary(); err != nil {
+			return err
+		}
+		if negate {
+			return p.emit(instruction{op: opNeg})
+		}
+		return nil
+	}
+	return p.parsePower()
+}
+
+// parsePower parses a ^ b, which groups to the right as in z^2^3 = z^(2^3).
+func (p *expressionParser) parsePower() error {
+	if err := p.parsePrimary(); err != nil {
+		return err
+	}
+	if !p.isOperator("^") {
+		return nil
+	}
+	if err := p.next(); err != nil {
+		return err
+	}
+	if err := p.parseUnary(); err != nil {
+		return err
+	}
+	return p.emit(instruction{op: opPow})
+}
+
+// parsePrimary parses a number, a name, a function call or a parenthesized
+// expression.
+func (p *expressionParser) parsePrimary() error {
+	switch {
+	case p.token.kind == tokenNumber:
+		value := p.token.value
+		if err := p.next(); err != nil {
+			return err
+		}
+		return p.emitConstant(value)
+
+	case p.token.kind == tokenName:
+		name, at := p.token.text, p.token.at
+		if err := p.next(); err != nil {
+			return err
+		}
+
+		if function, ok := expressionFunctions[name]; ok {
+			if !p.isOperator("(") {
+				return p.errorf("expected ( after %s", name)
+			}
+			if err := p.parseParenthesized(); err != nil {
+				return err
+			}
+			p.expression.functions = append(p.expression.functions, function)
+			return p.emit(instruction{op: opCall, arg: len(p.expression.functions) - 1})
+		}
+
+		switch name {
+		case "z":
+			return p.emit(instruction{op: opZ})
+		case "c":
+			return p.emit(instruction{op: opC})
+		case "pixel":
+			return p.emit(instruction{op: opPixel})
+		}
+		if value, ok := expressionConstants[name]; ok {
+			return p.emitConstant(value)
+		}
+		p.token.at = at
+		return p.errorf("unknown name %q", name)
+
+	case p.isOperator("("):
+		return p.parseParenthesized()
+	}
+
+	return p.errorf("unexpected %s", p.token.text)
+}
+
+func (p *expressionParser) parseParenthesized() error {
+	if err := p.next(); err != nil {
+		return err
+	}
+	if err := p.parseSum(); err != nil {
+		return err
+	}
+	if !p.isOperator(")") {
+		return p.errorf("expected ) but found %s", p.token.text)
+	}
+	return p.next()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"math"
+	"math/cmplx"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func evalExpression(t *testing.T, text string, z, c complex128) complex128 {
+	t.Helper()
+	expression, err := CompileExpression(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expression.Eval(expression.NewStack(), z, c, 0)
+}
+
+func TestExpressionEval(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		z, c complex128
+		want complex128
+	}{
+		// Precedence and grouping.
+		{"1 + 2*3", 0, 0, 7},
+		{"(1 + 2)*3", 0, 0, 9},
+		{"2*3^2", 0, 0, 18},
+		{"8 - 2 - 2", 0, 0, 4},
+		{"8/2/2", 0, 0, 2},
+		{"2^3^2", 0, 0, 512},
+		{"-2^2", 0, 0, -4},
+		{"2^-1", 0, 0, 0.5},
+		{"z^2 + c", 2i, 1, -3},
+
+		// Implicit multiplication after a number, which leaves exponents
+		// alone.
+		{"2z", 3, 0, 6},
+		{"3(z + 1)", 1, 0, 6},
+		{"2exp(z)", 0, 0, 2},
+		{"2e", 0, 0, 2 * math.E},
+		{"1e-3", 0, 0, 0.001},
+		{"1e3z", 2, 0, 2000},
+		{"2i", 0, 0, 2i},
+		{"2i*z", 1i, 0, -2},
+
+		{"conj(z)^2 + c", 1 + 1i, 0, -2i},
+		{"abs(z) + re(c) + im(c)", 3 + 4i, 1 + 2i, 8},
+	} {
+		if got := evalExpression(t, test.text, test.z, test.c); cmplx.Abs(got-test.want) > 1e-12 {
+			t.Errorf("%s at z = %v, c = %v is %v, want %v", test.text, test.z, test.c, got, test.want)
+		}
+	}
+}
+
+// TestExpressionFoldsConstants checks that the constant parts of an
+// expression are evaluated once when compiling it.
+func TestExpressionFoldsConstants(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		code []opcode
+	}{
+		{"2*3 + z", []opcode{opConst, opZ, opAdd}},
+		{"sin(0) + -(2) + z", []opcode{opConst, opZ, opAdd}},
+		{"z^2 + c", []opcode{opZ, opPowConst, opC, opAdd}},
+		{"z^(1+1)", []opcode{opZ, opPowConst}},
+		{"z^i", []opcode{opZ, opConst, opPow}},
+		{"(2 + 3i)*(pi - 1)", []opcode{opConst}},
+	} {
+		expression, err := CompileExpression(test.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var code []opcode
+		for _, in := range expression.code {
+			code = append(code, in.op)
+		}
+		if !slices.Equal(code, test.code) {
+			t.Errorf("%s compiles to %v, want %v", test.text, code, test.code)
+		}
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	for _, test := range []struct {
+		text, err string
+	}{
+		{"z + $", `unexpected "$" at column 5 of "z + $"`},
+		{"z + foo", `unknown name "foo" at column 5 of "z + foo"`},
+		{"sin z", `expected ( after sin at column 5 of "sin z"`},
+		{"(z + 1", `expected ) but found end at column 7 of "(z + 1"`},
+		{"z +", `unexpected end at column 4 of "z +"`},
+		{"z c", `unexpected "c" at column 3 of "z c"`},
+		{"1..2 + z", `invalid number "1..2" at column 1 of "1..2 + z"`},
+	} {
+		if _, err := CompileExpression(test.text); err == nil || err.Error() != test.err {
+			t.Errorf("%s: got error %v, want %s", test.text, err, test.err)
+		}
+	}
+}
+
+// TestExpressionStack checks that the stack is sized for the most values an
+// expression keeps at once, up to maxExpressionStack.
+func TestExpressionStack(t *testing.T) {
+	// nested keeps n values on the stack: z + (z + (... + z)).
+	nested := func(n int) string {
+		return strings.Repeat("z + (", n-1) + "z" + strings.Repeat(")", n-1)
+	}
+
+	for _, test := range []struct {
+		text      string
+		stackSize int
+	}{
+		{"z", 1},
+		{"z^2 + c", 2},
+		{"z*z + z*z", 3},
+		{nested(10), 10},
+		{nested(maxExpressionStack), maxExpressionStack},
+	} {
+		expression, err := CompileExpression(test.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expression.stackSize != test.stackSize {
+			t.Errorf("%s needs a stack of %d, want %d", test.text, expression.stackSize, test.stackSize)
+		}
+	}
+
+	if got := evalExpression(t, nested(maxExpressionStack), 1, 0); got != maxExpressionStack {
+		t.Errorf("the deepest expression evaluates to %v, want %d", got, maxExpressionStack)
+	}
+	if _, err := CompileExpression(nested(maxExpressionStack + 1)); err == nil || !strings.Contains(err.Error(), "expression nests too deeply") {
+		t.Errorf("got error %v for an expression nesting too deeply", err)
+	}
+}
@@ -2,174 +2,170 @@ package main
 
 import (
 	"context"
-	"image"
+	"flag"
 	"math"
 )
 
 type FastFloatEngine struct {
-	fzr           [][][][]float64
-	fzi           [][][][]float64
-	fzr2          [][][][]float64
-	fzi2          [][][][]float64
-	excluded      [][]bool
-	explodesAt    [][][][]int
-	image         *image.RGBA
-	maxExplodesAt int
+	*engineCore
 
-	width, height              int
-	scale                      int
-	scaleFactorX, scaleFactorY float64
+	fzr  []float64
+	fzi  []float64
+	fzr2 []float64
+	fzi2 []float64
 
-	centerX, centerY float64
+	// fzrPrev and fziPrev hold the previous z of the Phoenix formula, they
+	// are nil for the others.
+	fzrPrev, fziPrev []float64
 
-	subIterations int
-
-	chunkSizeX, chunkSizeY int
-
-	iterations int
-
-	stopped bool
+	formula Formula
+	power   float64
+	phoenix float64
 }
 
 type FastFloatEngineParams struct {
-	Width, Height          int
-	CenterX, CenterY       *float64
-	Scale                  *int
-	SubIterations          *int
-	ChunkSizeX, ChunkSizeY *int
+	EngineParams
+	Formula *Formula
+	Power   *float64
+	Phoenix *float64
+}
+
+func init() {
+	RegisterEngine(EngineDefinition{
+		Name: "fast",
+		Flags: func(flags *flag.FlagSet, params *cliParams) {
+			flags.Float64Var(&params.phoenix, "phoenix", -0.5, "the p of the phoenix formula z = z^2 + c + p z_prev")
+		},
+		Julia:    true,
+		Formulas: true,
+		Powers:   true,
+		New: func(params cliParams) Engine {
+			formula, _ := ParseFormula(params.formula)
+			return NewFastFloatEngine(FastFloatEngineParams{
+				EngineParams: params.engineParams(),
+				Formula:      &formula,
+				Power:        &params.power,
+				Phoenix:      &params.phoenix,
+			})
+		},
+	})
 }
 
 func NewFastFloatEngine(params FastFloatEngineParams) *FastFloatEngine {
 	engine := FastFloatEngine{
-		width:         params.Width,
-		height:        params.Height,
-		fzr:           Create4D[float64](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1)),
-		fzi:           Create4D[float64](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1)),
-		fzr2:          Create4D[float64](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1)),
-		fzi2:          Create4D[float64](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1)),
-		explodesAt:    Create4D[int](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX, Elvis(params.ChunkSizeX, 1), Elvis(params.ChunkSizeY, 1)),
-		excluded:      Create2D[bool](params.Height / *params.ChunkSizeY, params.Width / *params.ChunkSizeX),
-		maxExplodesAt: 1,
-		scale:         Elvis(params.Scale, 1),
-		scaleFactorX:  float64(3) / float64(params.Width*Elvis(params.Scale, 1)),
-		scaleFactorY:  (float64(3*params.Height) / float64(params.Width)) / float64(params.Width*Elvis(params.Scale, 1)),
-		centerX:       Elvis(params.CenterX, 0.75),
-		centerY:       Elvis(params.CenterY, 0),
-		subIterations: Elvis(params.SubIterations, 100),
-		iterations:    1,
-		chunkSizeX:    Elvis(params.ChunkSizeX, 1),
-		chunkSizeY:    Elvis(params.ChunkSizeY, 1),
-		image:         image.NewRGBA(image.Rect(0, 0, params.Width, params.Height)),
+		engineCore: newEngineCore(params.EngineParams),
+		formula:    Elvis(params.Formula, Mandelbrot),
+		power:      Elvis(params.Power, 2),
+		phoenix:    Elvis(params.Phoenix, -0.5),
 	}
+	engine.fzr = newPixels[float64](&engine.chunkGrid)
+	engine.fzi = newPixels[float64](&engine.chunkGrid)
+	engine.fzr2 = newPixels[float64](&engine.chunkGrid)
+	engine.fzi2 = newPixels[float64](&engine.chunkGrid)
+
+	if engine.formula == Phoenix {
+		engine.fzrPrev = newPixels[float64](&engine.chunkGrid)
+		engine.fziPrev = newPixels[float64](&engine.chunkGrid)
+	}
+
+	engine.seedJulia(func(p int, px, py int32) {
+		zr, zi := engine.toPlane(px, py)
+		engine.fzr[p], engine.fzi[p] = zr, zi
+		engine.fzr2[p], engine.fzi2[p] = zr*zr, zi*zi
+	})
 
 	return &engine
 }
 
 func (f *FastFloatEngine) Perform(context context.Context, x, y int32) {
-	X := x * int32(f.chunkSizeX)
-	Y := y * int32(f.chunkSizeY)
-
-	performCount := 0
-	for _x := range int32(f.chunkSizeX) {
-		for _y := range int32(f.chunkSizeY) {
-			select {
-			case <-context.Done():
-				return
-
-			default:
-				XX := X + _x
-				YY := Y + _y
-
-				if f.explodesAt[x][y][_x][_y] == 0 {
-					performCount++
-					dXX := float64(XX - int32(f.width/2))
-					dYY := float64(YY - int32(f.height/2))
-					_XX := f.centerX + dXX*f.scaleFactorX
-					_YY := f.centerY + dYY*f.scaleFactorY
-
-					history_r_0 := -1.0
-					history_i_0 := -1.0
-					history_r_1 := -1.0
-					history_i_1 := -1.0
-					history_r_2 := -1.0
-					history_i_2 := -1.0
-
-					for i := range f.subIterations {
-						z1r := f.fzr2[x][y][_x][_y]
-						z1i := f.fzi2[x][y][_x][_y]
-
-						if z1r+z1i > 4 {
-							f.explodesAt[x][y][_x][_y] = f.iterations + i
-							f.maxExplodesAt = max(f.maxExplodesAt, f.explodesAt[x][y][_x][_y])
-							break
-						}
-
-						z3i := float64(2)*f.fzr[x][y][_x][_y]*f.fzi[x][y][_x][_y] + _YY
-						z3r := f.fzr2[x][y][_x][_y] - f.fzi2[x][y][_x][_y] + _XX
-
-						if (math.Abs(history_r_0-z3r)+math.Abs(history_i_0-z3i) < 0.0001) ||
-							(math.Abs(history_r_1-z3r)+math.Abs(history_i_1-z3i) < 0.0001) {
-							f.explodesAt[x][y][_x][_y] = -1
-						}
-
-						history_r_0, history_i_0 = history_r_1, history_i_1
-						history_r_1, history_i_1 = history_r_2, history_i_2
-						history_r_2, history_i_2 = z3r, z3i
-
-						f.fzr[x][y][_x][_y], f.fzi[x][y][_x][_y], f.fzr2[x][y][_x][_y], f.fzi2[x][y][_x][_y] = z3r, z3i, z3r*z3r, z3i*z3i
-					}
-				}
-			}
-		}
-	}
-
-	if performCount == 0 {
-		f.excluded[x][y] = true
-	}
-}
+	escapeRadius2 := f.escapeRadius * f.escapeRadius
 
-func (f *FastFloatEngine) CanSkipChunk(x, y int32) bool {
-	return f.excluded[x][y]
-}
+	f.performChunk(context, x, y, func(p int, px, py int32) {
+		_XX, _YY := f.parameter(f.toPlane(px, py))
 
-func (f *FastFloatEngine) GetChunkedArea() int {
-	return (f.width / f.chunkSizeX) * (f.height / f.chunkSizeY)
-}
+		zr, zi, zr2, zi2 := f.fzr[p], f.fzi[p], f.fzr2[p], f.fzi2[p]
+		var zrPrev, ziPrev float64
+		if f.formula == Phoenix {
+			zrPrev, ziPrev = f.fzrPrev[p], f.fziPrev[p]
+		}
 
-func (f *FastFloatEngine) GetExplodesAt(x, y int32) int {
-	xx := x / int32(f.chunkSizeX)
-	xy := x % int32(f.chunkSizeX)
-	yx := y / int32(f.chunkSizeY)
-	yy := y % int32(f.chunkSizeY)
+		history_r_0 := -1.0
+		history_i_0 := -1.0
+		history_r_1 := -1.0
+		history_i_1 := -1.0
+		history_r_2 := -1.0
+		history_i_2 := -1.0
+
+		for i := range f.subIterations {
+			if zr2+zi2 > escapeRadius2 {
+				f.escape(p, i, math.Sqrt(zr2+zi2))
+				break
+			}
 
-	ans := f.explodesAt[xx][yx][xy][yy]
-	return ans
-}
+			var z3r, z3i float64
+			if f.power == 2 {
+				switch f.formula {
+				case BurningShip:
+					z3i = float64(2)*math.Abs(zr*zi) + _YY
+				case Tricorn:
+					z3i = float64(-2)*zr*zi + _YY
+				case PerpendicularBurningShip:
+					z3i = float64(-2)*zr*math.Abs(zi) + _YY
+				default:
+					z3i = float64(2)*zr*zi + _YY
+				}
+				z3r = zr2 - zi2
+				if f.formula == Celtic {
+					z3r = math.Abs(z3r)
+				}
+				z3r += _XX
+			} else {
+				z := complex(zr, zi)
+				switch f.formula {
+				case BurningShip:
+					z = complex(math.Abs(real(z)), math.Abs(imag(z)))
+				case Tricorn:
+					z = complex(real(z), -imag(z))
+				case PerpendicularBurningShip:
+					z = complex(real(z), -math.Abs(imag(z)))
+				}
+				z3 := pow(z, f.power)
+				if f.formula == Celtic {
+					z3 = complex(math.Abs(real(z3)), imag(z3))
+				}
+				z3 += complex(_XX, _YY)
+				z3r, z3i = real(z3), imag(z3)
+			}
 
-func (f FastFloatEngine) GetMaxExplodesAt() int {
-	return f.maxExplodesAt
-}
+			if f.formula == Phoenix {
+				z3r += f.phoenix * zrPrev
+				z3i += f.phoenix * ziPrev
+				zrPrev, ziPrev = zr, zi
+			}
 
-func (f *FastFloatEngine) IncreaseIteration() {
-	f.iterations += f.subIterations
-}
+			// A repeating z only means a cycle if the whole state
+			// repeats, which for Phoenix includes the previous z.
+			if f.formula != Phoenix && ((math.Abs(history_r_0-z3r)+math.Abs(history_i_0-z3i) < 0.0001) ||
+				(math.Abs(history_r_1-z3r)+math.Abs(history_i_1-z3i) < 0.0001)) {
+				f.explodesAt[p] = -1
+			}
 
-func (f *FastFloatEngine) GetIterations() int {
-	return f.iterations
-}
+			history_r_0, history_i_0 = history_r_1, history_i_1
+			history_r_1, history_i_1 = history_r_2, history_i_2
+			history_r_2, history_i_2 = z3r, z3i
 
-func (f *FastFloatEngine) ResetImage() {
-	f.image = image.NewRGBA(image.Rect(0, 0, f.width, f.height))
-}
+			zr, zi, zr2, zi2 = z3r, z3i, z3r*z3r, z3i*z3i
+		}
 
-func (f *FastFloatEngine) GetImage() *image.RGBA {
-	return f.image
+		f.fzr[p], f.fzi[p], f.fzr2[p], f.fzi2[p] = zr, zi, zr2, zi2
+		if f.formula == Phoenix {
+			f.fzrPrev[p], f.fziPrev[p] = zrPrev, ziPrev
+		}
+	})
 }
 
-func (f *FastFloatEngine) IsStopped() bool {
-	return f.stopped
-}
+func (f *FastFloatEngine) GetSmoothExplodesAt(x, y int32) float64 {
+	p := f.pixelIndex(x, y)
 
-func (f *FastFloatEngine) Stop() {
-	f.stopped = true
+	return smoothIterationOfDegree(f.explodesAt[p], f.escapeModulus[p], f.escapeRadius, f.power)
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+const (
+	benchmarkSize  = 512
+	benchmarkChunk = 32
+)
+
+func newBenchmarkEngine(formula Formula, julia bool) *FastFloatEngine {
+	return NewFastFloatEngine(FastFloatEngineParams{
+		EngineParams: EngineParams{
+			Width:         benchmarkSize,
+			Height:        benchmarkSize,
+			SubIterations: Ptr(100),
+			ChunkSizeX:    Ptr(benchmarkChunk),
+			ChunkSizeY:    Ptr(benchmarkChunk),
+			Julia:         Ptr(julia),
+			CReal:         Ptr(-0.4),
+			CImag:         Ptr(0.6),
+		},
+		Formula: &formula,
+	})
+}
+
+// performAll runs one iteration over every chunk of the image.
+func performAll(engine Engine) {
+	chunks := int32(benchmarkSize / benchmarkChunk)
+	for x := range chunks {
+		for y := range chunks {
+			engine.Perform(context.Background(), x, y)
+		}
+	}
+}
+
+func BenchmarkNewFastFloatEngine(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		newBenchmarkEngine(Mandelbrot, false)
+	}
+}
+
+func BenchmarkFastFloatEnginePerform(b *testing.B) {
+	for _, bench := range []struct {
+		name    string
+		formula Formula
+		julia   bool
+	}{
+		{"mandelbrot", Mandelbrot, false},
+		{"burningship", BurningShip, false},
+		{"julia", Mandelbrot, true},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for range b.N {
+				b.StopTimer()
+				engine := newBenchmarkEngine(bench.formula, bench.julia)
+				b.StartTimer()
+
+				performAll(engine)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// Formula selects the iteration the fast float engine performs.
+type Formula int
+
+const (
+	// Mandelbrot iterates z = z² + c.
+	Mandelbrot Formula = iota
+	// BurningShip iterates z = (|Re z| + i|Im z|)² + c. The imaginary axis
+	// points down the image, which shows the ship upright.
+	BurningShip
+	// Tricorn iterates z = conj(z)² + c.
+	Tricorn
+	// Celtic iterates z = |Re z²| + i Im z² + c.
+	Celtic
+	// PerpendicularBurningShip iterates z = (Re z - i|Im z|)² + c.
+	PerpendicularBurningShip
+	// Phoenix iterates z = z² + c + p z₋₁, where z₋₁ is the previous z. It
+	// is mostly seen as the Julia set of c = 0.5667, p = -0.5.
+	Phoenix
+)
+
+// FormulaView is where a formula is best looked at before zooming in.
+type FormulaView struct {
+	CenterX, CenterY string
+	Scale            string
+}
+
+type formulaDefinition struct {
+	name string
+	view FormulaView
+}
+
+var formulas = map[Formula]formulaDefinition{
+	Mandelbrot:               {name: "mandelbrot", view: FormulaView{CenterX: "-0.75", CenterY: "0", Scale: "1"}},
+	BurningShip:              {name: "burningship", view: FormulaView{CenterX: "-0.45", CenterY: "-0.5", Scale: "0.9"}},
+	Tricorn:                  {name: "tricorn", view: FormulaView{CenterX: "-0.3", CenterY: "0", Scale: "0.85"}},
+	Celtic:                   {name: "celtic", view: FormulaView{CenterX: "-0.5", CenterY: "0", Scale: "0.9"}},
+	PerpendicularBurningShip: {name: "perpendicular", view: FormulaView{CenterX: "-0.5", CenterY: "0", Scale: "0.9"}},
+	Phoenix:                  {name: "phoenix", view: FormulaView{CenterX: "0", CenterY: "0", Scale: "1"}},
+}
+
+func ParseFormula(name string) (Formula, bool) {
+	for formula, definition := range formulas {
+		if definition.name == name {
+			return formula, true
+		}
+	}
+	return Mandelbrot, false
+}
+
+func (f Formula) String() string {
+	return formulas[f].name
+}
+
+func (f Formula) DefaultView() FormulaView {
+	return formulas[f].view
+}
+
+// formulaNames lists the formulas for the usage of the -formula flag.
+func formulaNames() string {
+	names := make([]string, 0, len(formulas))
+	for _, definition := range formulas {
+		names = append(names, definition.name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, "/")
+}
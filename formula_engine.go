@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+)
+
+// FormulaEngine iterates a user-supplied Expression, z = f(z, c, pixel), so
+// new fractals can be tried out without writing an engine for them.
+type FormulaEngine struct {
+	*engineCore
+
+	z []complex128
+
+	expression *Expression
+}
+
+type FormulaEngineParams struct {
+	EngineParams
+	Expression *Expression
+}
+
+func init() {
+	RegisterEngine(EngineDefinition{
+		Name: "expr",
+		Flags: func(flags *flag.FlagSet, params *cliParams) {
+			flags.StringVar(&params.expr, "expr", "z^2 + c", "iteration of the expr engine in z, c and pixel, e.g. \"conj(z)^2 + c\"")
+		},
+		Verify: func(params cliParams) error {
+			if _, err := CompileExpression(params.expr); err != nil {
+				return fmt.Errorf("invalid expression: %w", err)
+			}
+			return nil
+		},
+		Julia: true,
+		New: func(params cliParams) Engine {
+			expression, _ := CompileExpression(params.expr)
+			return NewFormulaEngine(FormulaEngineParams{
+				EngineParams: params.engineParams(),
+				Expression:   expression,
+			})
+		},
+	})
+}
+
+func NewFormulaEngine(params FormulaEngineParams) *FormulaEngine {
+	engine := FormulaEngine{
+		engineCore: newEngineCore(params.EngineParams),
+		expression: params.Expression,
+	}
+	engine.z = newPixels[complex128](&engine.chunkGrid)
+
+	engine.seedJulia(func(p int, px, py int32) {
+		engine.z[p] = complex(engine.toPlane(px, py))
+	})
+
+	return &engine
+}
+
+func (f *FormulaEngine) Perform(context context.Context, x, y int32) {
+	escapeRadius2 := f.escapeRadius * f.escapeRadius
+	stack := f.expression.NewStack()
+
+	f.performChunk(context, x, y, func(p int, px, py int32) {
+		re, im := f.toPlane(px, py)
+		pixel := complex(re, im)
+		c := complex(f.parameter(re, im))
+
+		z := f.z[p]
+		for i := range f.subIterations {
+			modulus2 := real(z)*real(z) + imag(z)*imag(z)
+
+			// NaN compares false, so an orbit that broke down, as on a
+			// division by zero, counts as escaped too.
+			if !(modulus2 <= escapeRadius2) {
+				if math.IsNaN(modulus2) || math.IsInf(modulus2, 1) {
+					modulus2 = escapeRadius2
+				}
+				f.escape(p, i, math.Sqrt(modulus2))
+				break
+			}
+
+			z = f.expression.Eval(stack, z, c, pixel)
+		}
+		f.z[p] = z
+	})
+}
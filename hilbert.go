@@ -14,7 +14,7 @@ func NewHilbertCurveSampler(n, m int) *HilbertCurveSampler {
 
 	// Initialize Hilbert curve points
 	for i := range n * m {
-		hc.samplePoint[i] = hc.Sample2(i, n, m)
+		hc.samplePoint[i] = hilbertSample(i, n, m)
 	}
 
 	return hc
@@ -24,22 +24,47 @@ func (h *HilbertCurveSampler) Sample(i int) Pair {
 	return h.samplePoint[i]
 }
 
-// HilbertPoint returns the i-th point in the Hilbert curve
-// for a 2D space of size n x Samplem
-func (h *HilbertCurveSampler) Sample2(i, n, m int) Pair {
-	// Find the smallest power of 2 that covers both dimensions
+// hilbertSample returns the i-th cell of an n x m grid along the Hilbert
+// curve filling the smallest power of two square around it. The curve leaves
+// the grid unless it is that square, the cells outside are skipped so that
+// every cell of the grid comes up exactly once.
+func hilbertSample(i, n, m int) Pair {
 	size := 1
 	for size < m || size < n {
 		size *= 2
 	}
 
+	// The curve visits the s x s blocks of the square one after the other,
+	// so descend into the block holding the i-th cell inside the grid,
+	// counting the cells of the blocks passed over.
+	d := 0
+	for s := size / 2; s >= 1; s /= 2 {
+		for {
+			x, y := hilbertPoint(d, size)
+			x0, y0 := x&^(s-1), y&^(s-1)
+			inside := max(min(x0+s, m)-x0, 0) * max(min(y0+s, n)-y0, 0)
+			if i < inside {
+				break
+			}
+			i -= inside
+			d += s * s
+		}
+	}
+
+	x, y := hilbertPoint(d, size)
+	return Pair{x: int32(x), y: int32(y)}
+}
+
+// hilbertPoint returns the point at distance d along the Hilbert curve of a
+// size x size square, size being a power of two.
+func hilbertPoint(d, size int) (int, int) {
 	// Initialize point
 	var x, y int
 
-	// Convert i to x,y coordinates using bit manipulation
+	// Convert d to x,y coordinates using bit manipulation
 	for s := 1; s < size; s *= 2 {
-		rx := 1 & (i / 2)
-		ry := 1 & (i ^ rx)
+		rx := 1 & (d / 2)
+		ry := 1 & (d ^ rx)
 
 		// Rotate quadrant if needed
 		if ry == 0 {
@@ -53,16 +78,8 @@ func (h *HilbertCurveSampler) Sample2(i, n, m int) Pair {
 
 		x += s * rx
 		y += s * ry
-		i /= 4
+		d /= 4
 	}
 
-	// Ensure the point is within bounds
-	if x >= m {
-		x = m - 1
-	}
-	if y >= n {
-		y = n - 1
-	}
-
-	return Pair{x: int32(x), y: int32(y)}
+	return x, y
 }
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ericlagergren/decimal"
+)
+
+// Location is a complete, shareable description of a view. The center and
+// zoom are kept as decimals so deep zooms survive a round trip exactly.
+// Phoenix is a pointer since p = 0 differs from its default, and Series is
+// the series approximation of the perturbation engine.
+type Location struct {
+	Engine        string       `json:"engine"`
+	Formula       string       `json:"formula,omitempty"`
+	Power         float64      `json:"power,omitempty"`
+	Phoenix       *float64     `json:"phoenix,omitempty"`
+	Poly          string       `json:"poly,omitempty"`
+	Expr          string       `json:"expr,omitempty"`
+	Limits        string       `json:"limits,omitempty"`
+	Anti          bool         `json:"anti,omitempty"`
+	Seq           string       `json:"seq,omitempty"`
+	Series        bool         `json:"series,omitempty"`
+	CenterX       string       `json:"centerX"`
+	CenterY       string       `json:"centerY"`
+	Zoom          json.Number  `json:"zoom"`
+	Angle         float64      `json:"angle,omitempty"`
+	Iterations    int          `json:"iterations"`
+	SubIterations int          `json:"subIterations"`
+	Julia         bool         `json:"julia,omitempty"`
+	CReal         float64      `json:"cr,omitempty"`
+	CImag         float64      `json:"ci,omitempty"`
+	EscapeRadius  float64      `json:"escapeRadius,omitempty"`
+	Bailout       float64      `json:"bailout,omitempty"`
+	Render        string       `json:"render,omitempty"`
+	Smooth        bool         `json:"smooth,omitempty"`
+	Palette       Palette      `json:"palette"`
+	ColorMapping  ColorMapping `json:"colorMapping"`
+}
+
+type Palette struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+}
+
+// ColorMapping holds the parameters of ExponentialMappedModuloColorRangeConverer.
+type ColorMapping struct {
+	Exponent float64 `json:"exponent"`
+	Steps    int     `json:"steps"`
+}
+
+func LoadLocation(path string) (Location, error) {
+	var location Location
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return location, err
+	}
+
+	err = json.Unmarshal(data, &location)
+	return location, err
+}
+
+// LoadLocationFromPNG reads the location embedded into an image saved by
+// this program.
+func LoadLocationFromPNG(path string) (Location, error) {
+	var location Location
+
+	f, err := os.Open(path)
+	if err != nil {
+		return location, err
+	}
+	defer f.Close()
+
+	text, err := ReadPNGText(f)
+	if err != nil {
+		return location, err
+	}
+
+	data, ok := text[locationKeyword]
+	if !ok {
+		return location, errors.New(path + " carries no location")
+	}
+
+	err = json.Unmarshal([]byte(data), &location)
+	return location, err
+}
+
+func SaveLocation(path string, location Location) error {
+	data, err := json.MarshalIndent(location, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func locationOf(params cliParams) Location {
+	location := Location{
+		Engine:        params.engine,
+		Formula:       params.formula,
+		Power:         params.power,
+		CenterX:       params.centerX,
+		CenterY:       params.centerY,
+		Zoom:          zoomOf(params.scale),
+		Angle:         params.angle,
+		Iterations:    params.iterations,
+		SubIterations: params.subiterations,
+		Julia:         params.julia,
+		EscapeRadius:  params.escapeRadius,
+		Render:        params.render,
+		Smooth:        params.smooth,
+		Palette:       Palette{Name: params.colorOf},
+		ColorMapping:  ColorMapping{Exponent: params.colorExponent, Steps: params.colorSteps},
+	}
+
+	if params.julia {
+		location.CReal, location.CImag = params.cr, params.ci
+	}
+	if params.formula == Phoenix.String() {
+		location.Phoenix = &params.phoenix
+	}
+	if params.engine == "derbail" {
+		location.Bailout = params.bailout
+	}
+	if params.engine == "perturbation" {
+		location.Series = params.seriesApproximation
+	}
+	if params.engine == "newton" {
+		location.Poly = params.poly
+	}
+	if params.engine == "expr" {
+		location.Expr = params.expr
+	}
+	if params.engine == "buddhabrot" {
+		location.Limits, location.Anti = params.limits, params.anti
+	}
+	if params.engine == "lyapunov" {
+		location.Seq = params.seq
+	}
+	if params.colorOf == "gradient" {
+		location.Palette.Path = params.colorGradientPath
+	}
+	return location
+}
+
+// zoomOf normalizes the zoom level into a valid JSON number, as the command
+// line also accepts forms like ".5".
+func zoomOf(scale string) json.Number {
+	zoom, ok := new(decimal.Big).SetString(scale)
+	if !ok {
+		return json.Number(scale)
+	}
+	return json.Number(zoom.String())
+}
+
+// applyTo overwrites params with every field the location sets.
+func (l Location) applyTo(params *cliParams) {
+	if l.Engine != "" {
+		params.engine = l.Engine
+	}
+	params.formula = Mandelbrot.String()
+	if l.Formula != "" {
+		params.formula = l.Formula
+	}
+	params.power = 2
+	if l.Power != 0 {
+		params.power = l.Power
+	}
+	if l.Phoenix != nil {
+		params.phoenix = *l.Phoenix
+	}
+	if l.Poly != "" {
+		params.poly = l.Poly
+	}
+	if l.Expr != "" {
+		params.expr = l.Expr
+	}
+	if l.Limits != "" {
+		params.limits = l.Limits
+	}
+	params.anti = l.Anti
+	if l.Seq != "" {
+		params.seq = l.Seq
+	}
+	params.seriesApproximation = l.Series
+	if l.CenterX != "" {
+		params.centerX = l.CenterX
+	}
+	if l.CenterY != "" {
+		params.centerY = l.CenterY
+	}
+	if l.Zoom != "" {
+		params.scale = l.Zoom.String()
+	}
+	params.angle = l.Angle
+	if l.Iterations != 0 {
+		params.iterations = l.Iterations
+	}
+	if l.SubIterations != 0 {
+		params.subiterations = l.SubIterations
+	}
+	params.julia = l.Julia
+	if l.Julia {
+		params.cr, params.ci = l.CReal, l.CImag
+	}
+	if l.EscapeRadius != 0 {
+		params.escapeRadius = l.EscapeRadius
+	}
+	if l.Bailout != 0 {
+		params.bailout = l.Bailout
+	}
+	if l.Render != "" {
+		params.render = l.Render
+	}
+	params.smooth = l.Smooth
+	if l.Palette.Name != "" {
+		params.colorOf = l.Palette.Name
+		params.colorGradientPath = l.Palette.Path
+	}
+	if l.ColorMapping.Exponent != 0 {
+		params.colorExponent = l.ColorMapping.Exponent
+	}
+	if l.ColorMapping.Steps != 0 {
+		params.colorSteps = l.ColorMapping.Steps
+	}
+}
+
+// pngTextOf describes the render for the text chunks of a saved image.
+func pngTextOf(params cliParams) map[string]string {
+	location := locationOf(params)
+	data, _ := json.Marshal(location)
+
+	return map[string]string{
+		"Software":      "go-julia",
+		"CenterX":       params.centerX,
+		"CenterY":       params.centerY,
+		"Scale":         params.scale,
+		"Angle":         fmt.Sprint(params.angle),
+		"Iterations":    fmt.Sprint(params.iterations * params.subiterations),
+		"Engine":        params.engine,
+		"Formula":       params.formula,
+		"Sampler":       params.sampler,
+		"Palette":       params.colorOf,
+		locationKeyword: string(data),
+	}
+}
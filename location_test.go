@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestLocationRoundTrip checks that saving a location and loading it onto
+// the defaults restores every parameter, also those equal to the zero value
+// of their type.
+func TestLocationRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		setup func(params *cliParams)
+	}{
+		{"phoenix", func(params *cliParams) {
+			params.engine, params.formula, params.phoenix = "fast", Phoenix.String(), 0
+			params.julia, params.cr, params.ci = true, 0.5667, 0
+		}},
+		{"series approximation", func(params *cliParams) {
+			params.engine, params.seriesApproximation = "perturbation", true
+			params.centerX, params.centerY, params.scale = "-0.743643887037151", "0.13182590420533", "1E+40"
+		}},
+		{"derbail", func(params *cliParams) {
+			params.engine, params.bailout, params.power = "derbail", 100, 3
+			params.render = "distance"
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			want := testParams("fast", 64, 64)
+			test.setup(&want)
+
+			path := filepath.Join(t.TempDir(), "location.json")
+			if err := SaveLocation(path, locationOf(want)); err != nil {
+				t.Fatal(err)
+			}
+			location, err := LoadLocation(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := testParams("fast", 64, 64)
+			location.applyTo(&got)
+			if got != want {
+				t.Errorf("loaded %+v, want %+v", got, want)
+			}
+		})
+	}
+}
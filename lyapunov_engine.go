@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// lyapunovWarmup is how many steps the logistic map takes to settle on its
+// attractor before they count towards the exponent.
+const lyapunovWarmup = 100
+
+// LyapunovEngine computes the Lyapunov exponent of the logistic map
+// x = r x (1 - x), where r follows a sequence of A and B, taking the values a
+// and b of the pixel. The real axis of the viewport holds a, the imaginary one
+// b. A negative exponent marks a stable pixel, a positive one a chaotic pixel.
+//
+// No pixel ever escapes, explodesAt counts the steps every pixel took.
+type LyapunovEngine struct {
+	*engineCore
+
+	x   []float64
+	sum []float64
+
+	// sequence holds true for every B.
+	sequence []bool
+}
+
+type LyapunovEngineParams struct {
+	EngineParams
+	Sequence []bool
+}
+
+// ParseLyapunovSequence reads a sequence such as "AABAB", in either case.
+func ParseLyapunovSequence(text string) ([]bool, error) {
+	if text == "" {
+		return nil, fmt.Errorf("empty sequence")
+	}
+
+	sequence := make([]bool, len(text))
+	for i, ch := range strings.ToUpper(text) {
+		switch ch {
+		case 'A':
+		case 'B':
+			sequence[i] = true
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q, only A and B are allowed", ch, text)
+		}
+	}
+	return sequence, nil
+}
+
+func init() {
+	RegisterEngine(EngineDefinition{
+		Name: "lyapunov",
+		Flags: func(flags *flag.FlagSet, params *cliParams) {
+			flags.StringVar(&params.seq, "seq", "AB", "sequence of A and B the lyapunov engine alternates r between, a along the x axis and b along the y axis")
+		},
+		Verify: func(params cliParams) error {
+			if _, err := ParseLyapunovSequence(params.seq); err != nil {
+				return fmt.Errorf("invalid sequence: %w", err)
+			}
+			return nil
+		},
+		// The Lyapunov fractal lives in the (a, b) square [2, 4]².
+		View: &FormulaView{CenterX: "3", CenterY: "3", Scale: "1.5"},
+		New: func(params cliParams) Engine {
+			sequence, _ := ParseLyapunovSequence(params.seq)
+			return NewLyapunovEngine(LyapunovEngineParams{
+				EngineParams: params.engineParams(),
+				Sequence:     sequence,
+			})
+		},
+	})
+}
+
+func NewLyapunovEngine(params LyapunovEngineParams) *LyapunovEngine {
+	core := params.EngineParams
+	core.CenterX = Ptr(Elvis(params.CenterX, 3))
+	core.CenterY = Ptr(Elvis(params.CenterY, 3))
+	core.Scale = Ptr(Elvis(params.Scale, 1.5))
+
+	engine := LyapunovEngine{
+		engineCore: newEngineCore(core),
+		sequence:   params.Sequence,
+	}
+	engine.x = newPixelsWithValue(&engine.chunkGrid, 0.5)
+	engine.sum = newPixels[float64](&engine.chunkGrid)
+
+	return &engine
+}
+
+func (f *LyapunovEngine) Perform(context context.Context, x, y int32) {
+	X := x * int32(f.chunkSizeX)
+	Y := y * int32(f.chunkSizeY)
+
+	p := f.index(x, y, 0, 0)
+	for py := Y; py < Y+int32(f.chunkSizeY); py++ {
+		for px := X; px < X+int32(f.chunkSizeX); px, p = px+1, p+1 {
+			select {
+			case <-context.Done():
+				return
+
+			default:
+				a, b := f.toPlane(px, py)
+
+				value := f.x[p]
+				sum := f.sum[p]
+				steps := f.explodesAt[p]
+				for range f.subIterations {
+					r := a
+					if f.sequence[steps%len(f.sequence)] {
+						r = b
+					}
+
+					if steps >= lyapunovWarmup {
+						sum += math.Log(math.Abs(r * (1 - 2*value)))
+					}
+					value = r * value * (1 - value)
+					steps++
+				}
+				f.x[p] = value
+				f.sum[p] = sum
+				f.explodesAt[p] = steps
+			}
+		}
+	}
+}
+
+// GetExponent returns the Lyapunov exponent of the pixel estimated so far, or
+// NaN while it is still warming up. It is +Inf once the orbit left [0, 1],
+// which it does for r > 4.
+func (f *LyapunovEngine) GetExponent(x, y int32) float64 {
+	p := f.pixelIndex(x, y)
+
+	counted := f.explodesAt[p] - lyapunovWarmup
+	if counted <= 0 {
+		return math.NaN()
+	}
+	return f.sum[p] / float64(counted)
+}
+
+func (f *LyapunovEngine) GetSmoothExplodesAt(x, y int32) float64 {
+	return float64(f.GetExplodesAt(x, y))
+}
+
+// GetColor shades stable pixels gold and chaotic ones blue, both fading to
+// black where the exponent nears zero, at the bifurcations.
+func (f *LyapunovEngine) GetColor(x, y int32, colorPicker ColorOf) color.RGBA {
+	exponent := f.GetExponent(x, y)
+	if math.IsNaN(exponent) || math.IsInf(exponent, 1) {
+		return color.RGBA{A: 255}
+	}
+
+	if exponent <= 0 {
+		level := math.Tanh(-exponent)
+		return color.RGBA{R: uint8(255 * level), G: uint8(200 * level), A: 255}
+	}
+	level := math.Tanh(exponent)
+	return color.RGBA{R: uint8(40 * level), G: uint8(80 * level), B: uint8(255 * level), A: 255}
+}
+
+func (f *LyapunovEngine) GetMaxExplodesAt() int {
+	return f.iterations
+}
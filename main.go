@@ -2,33 +2,65 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"image/png"
+	"image"
 	"log"
 	"math"
-	"os"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
-	"fyne.io/fyne/v2/canvas"
 	"github.com/alitto/pond/v2"
+	"github.com/ericlagergren/decimal"
 )
 
+// zoomStep is the factor one scroll wheel step zooms in or out by.
+const zoomStep = 2.0
+
+// rotateStep is the angle in degrees the [ and ] keys rotate the view by.
+const rotateStep = 15.0
+
 type cliParams struct {
 	width, height          int
 	chunkSizeX, chunkSizeY int
-	centerX, centerY       float64
+	centerX, centerY       string
 	bailout                float64
-	scale                  int
+	escapeRadius           float64
+	smooth                 bool
+	render                 string
+	headless               bool
+	out                    string
+	scale                  string
+	angle                  float64
 	subiterations          int
 	iterations             int
 	sampler                string
 	colorOf                string
 	colorGradientPath      string
+	colorExponent          float64
+	colorSteps             int
+	load                   string
+	fromPNG                string
+	locationOut            string
+	engine                 string
+	formula                string
+	power                  float64
+	phoenix                float64
+	poly                   string
+	expr                   string
+	limits                 string
+	seq                    string
+	anti                   bool
+	seriesApproximation    bool
+	julia                  bool
+	cr, ci                 float64
 }
 
 func verify(params cliParams) {
@@ -48,8 +80,22 @@ func verify(params cliParams) {
 		log.Fatal("Width and height must be powers of two")
 	}
 
-	if params.scale <= 0 {
-		log.Fatal("Scale must be a positive integer")
+	// The centers are decimals, which the deep zoom engines read with all of
+	// their digits and the others through viewport.
+	if centerX, ok := new(decimal.Big).SetString(params.centerX); !ok || !centerX.IsFinite() {
+		log.Fatalf("Invalid center X: %s", params.centerX)
+	}
+
+	if centerY, ok := new(decimal.Big).SetString(params.centerY); !ok || !centerY.IsFinite() {
+		log.Fatalf("Invalid center Y: %s", params.centerY)
+	}
+
+	if scale, ok := new(decimal.Big).SetString(params.scale); !ok || !scale.IsFinite() || scale.Sign() <= 0 {
+		log.Fatalf("Scale must be a positive finite number: %s", params.scale)
+	}
+
+	if params.escapeRadius <= 1 {
+		log.Fatal("Escape radius must be greater than one")
 	}
 
 	if params.subiterations <= 0 || params.iterations <= 0 {
@@ -60,6 +106,58 @@ func verify(params cliParams) {
 		log.Fatalf("Invalid sampler: %s. Supported samplers are %s", params.sampler, strings.Join([]string{"linear", "hilbert", "cachedhilbert"}, ","))
 	}
 
+	definition, ok := LookupEngine(params.engine)
+	if !ok {
+		log.Fatalf("Invalid engine: %s. Supported engines are %s", params.engine, engineNames())
+	}
+
+	for _, other := range engineDefinitions() {
+		if other.Name == params.engine {
+			continue
+		}
+		for _, name := range other.flagNames() {
+			if isFlagSet(name) {
+				log.Fatalf("-%s only applies to the %s engine, not to %s", name, other.Name, params.engine)
+			}
+		}
+	}
+
+	if _, err := params.viewport(); err != nil && !definition.DeepZoom {
+		log.Fatalf("The %s engine cannot render this view: %v", params.engine, err)
+	}
+
+	if definition.Verify != nil {
+		if err := definition.Verify(params); err != nil {
+			log.Fatalf("Invalid parameters for the %s engine: %v", params.engine, err)
+		}
+	}
+
+	if params.julia && !definition.Julia {
+		log.Fatalf("The %s engine has no Julia mode", params.engine)
+	}
+
+	if formula, ok := ParseFormula(params.formula); !ok {
+		log.Fatalf("Invalid formula: %s. Supported formulas are %s", params.formula, formulaNames())
+	} else if formula != Mandelbrot && !definition.Formulas {
+		log.Fatalf("The %s engine only iterates the mandelbrot formula", params.engine)
+	}
+
+	if params.power <= 1 {
+		log.Fatal("Power must be greater than one")
+	}
+
+	if params.power != 2 && !definition.Powers {
+		log.Fatalf("The %s engine only supports power 2", params.engine)
+	}
+
+	if params.render != "iterations" && params.render != "distance" {
+		log.Fatalf("Invalid render mode: %s. Supported render modes are iterations and distance", params.render)
+	}
+
+	if params.render == "distance" && !definition.Distance {
+		log.Fatalf("The %s engine has no distance rendering", params.engine)
+	}
+
 	if params.colorOf != "spectral" && params.colorOf != "gradient" {
 		log.Fatalf("Invalid color pickers: %s. Supported color pickers are spectral and gradient", params.sampler)
 	}
@@ -67,6 +165,122 @@ func verify(params cliParams) {
 	if params.colorGradientPath == "" && params.colorOf == "gradient" {
 		log.Fatal("Gradient color picker requires a valid gradient image path")
 	}
+
+	if params.colorExponent <= 0 || params.colorSteps <= 0 {
+		log.Fatal("Color exponent and steps must be positive")
+	}
+
+	if params.out == "" {
+		log.Fatal("Output path must not be empty")
+	}
+}
+
+// viewport returns the mapping between the image and the complex plane for
+// these parameters, with the center rounded to float64.
+func (p cliParams) viewport() (Viewport, error) {
+	centerX, err := strconv.ParseFloat(p.centerX, 64)
+	if err != nil || math.IsInf(centerX, 0) || math.IsNaN(centerX) {
+		return Viewport{}, fmt.Errorf("center X %s is not a finite float64", p.centerX)
+	}
+	centerY, err := strconv.ParseFloat(p.centerY, 64)
+	if err != nil || math.IsInf(centerY, 0) || math.IsNaN(centerY) {
+		return Viewport{}, fmt.Errorf("center Y %s is not a finite float64", p.centerY)
+	}
+	scale, err := strconv.ParseFloat(p.scale, 64)
+	if err != nil || math.IsInf(scale, 0) || math.IsNaN(scale) || scale <= 0 {
+		return Viewport{}, fmt.Errorf("scale %s is out of the range of a float64", p.scale)
+	}
+	return NewViewport(p.width, p.height, centerX, centerY, scale, p.angle), nil
+}
+
+// offsetCenter returns the center moved by share of the way to pixel
+// (px, py). The offset is taken at scale 1 and divided by the decimal scale,
+// so that it holds at zooms a float64 scale overflows at.
+func (p cliParams) offsetCenter(px, py, share float64) (string, string) {
+	dx, dy := NewViewport(p.width, p.height, 0, 0, 1, p.angle).Offset(px, py)
+	return OffsetDecimal(p.centerX, dx*share, p.scale), OffsetDecimal(p.centerY, dy*share, p.scale)
+}
+
+// engineParams returns the parameters shared by the engines iterating in
+// float64, whose viewport verify has checked.
+func (p cliParams) engineParams() EngineParams {
+	viewport, err := p.viewport()
+	if err != nil {
+		log.Fatal(err)
+	}
+	params := p.engineParamsWithoutView()
+	params.CenterX, params.CenterY, params.Scale = &viewport.CenterX, &viewport.CenterY, &viewport.Scale
+	return params
+}
+
+// engineParamsWithoutView leaves out the center and the scale, which deep
+// zoom engines take in decimal instead.
+func (p cliParams) engineParamsWithoutView() EngineParams {
+	return EngineParams{
+		Width:         p.width,
+		Height:        p.height,
+		Angle:         &p.angle,
+		SubIterations: &p.subiterations,
+		ChunkSizeX:    &p.chunkSizeX,
+		ChunkSizeY:    &p.chunkSizeY,
+		Julia:         &p.julia,
+		CReal:         &p.cr,
+		CImag:         &p.ci,
+		EscapeRadius:  &p.escapeRadius,
+	}
+}
+
+// performIteration performs one iteration of engine on a pool of workers,
+// a chunk per task in the order of sampler, and paints every chunk as soon as
+// it is done. Each chunk comes up exactly once, its task owns the state of
+// its pixels and their part of the image. An IterationFinisher finishes the
+// iteration once all chunks are done.
+func performIteration(context context.Context, engine Engine, sampler Sampler, chunkSizeX, chunkSizeY int, paint func(px, py int)) {
+	workerPool := pond.NewPool(128, pond.WithContext(context))
+
+	for k := range engine.GetChunkedArea() {
+		P := sampler.Sample(k)
+		x, y := P.x, P.y
+
+		if engine.IsStopped() {
+			break
+		}
+		if engine.CanSkipChunk(x, y) {
+			continue
+		}
+
+		workerPool.Submit(func() {
+			engine.Perform(context, x, y)
+
+			X := chunkSizeX * int(x)
+			Y := chunkSizeY * int(y)
+
+			for py := Y; py < Y+chunkSizeY; py++ {
+				for px := X; px < X+chunkSizeX; px++ {
+					if engine.IsStopped() {
+						return
+					}
+
+					paint(px, py)
+				}
+			}
+		})
+	}
+	workerPool.StopAndWait()
+
+	if finisher, ok := engine.(IterationFinisher); ok {
+		finisher.FinishIteration()
+	}
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
 func main() {
@@ -75,21 +289,112 @@ func main() {
 	flag.IntVar(&params.height, "height", 1024, "height of the image")
 	flag.IntVar(&params.chunkSizeX, "chunkX", 256, "chunk size in X direction")
 	flag.IntVar(&params.chunkSizeY, "chunkY", 256, "chunk size in Y direction")
-	flag.Float64Var(&params.centerX, "x", -0.75, "center offset X")
-	flag.Float64Var(&params.centerY, "y", 0, "center offset Y")
-	flag.IntVar(&params.scale, "scale", 1, "zoom level")
+	flag.StringVar(&params.centerX, "x", "-0.75", "center offset X, as a decimal of any precision")
+	flag.StringVar(&params.centerY, "y", "0", "center offset Y, as a decimal of any precision")
+	flag.StringVar(&params.scale, "scale", "1", "zoom level, as a decimal of any magnitude (e.g. 1e45)")
+	flag.Float64Var(&params.angle, "angle", 0, "rotation of the view in degrees, counter-clockwise")
 	flag.IntVar(&params.subiterations, "subit", 200, "sub-iterations per chunk")
 	flag.IntVar(&params.iterations, "it", 10, "max iterations")
 	flag.StringVar(&params.sampler, "sampler", "linear", "which sampler to use (linear/hilbert)")
 	flag.StringVar(&params.colorOf, "color", "spectral", "which color picker to use (spectral/gradient)")
 	flag.StringVar(&params.colorGradientPath, "path", ".", "if gradient color picker, the path of the image from which to sample the colors")
-	flag.Float64Var(&params.bailout, "bailout", 1e4, "bailout value for derbail engine")
+	flag.Float64Var(&params.colorExponent, "colorExponent", 1.1, "exponent of the iteration to color mapping")
+	flag.IntVar(&params.colorSteps, "colorSteps", 20, "number of times the palette repeats over the iteration range")
+	flag.Float64Var(&params.escapeRadius, "radius", 2, "escape radius, larger values give smoother gradients with -smooth; -render distance raises it to at least 1000")
+	flag.StringVar(&params.render, "render", "iterations", "what to shade pixels by (iterations/distance)")
+	flag.BoolVar(&params.smooth, "smooth", false, "color by the continuous (normalized) iteration count instead of the integer one")
+	flag.StringVar(&params.engine, "engine", "fast", fmt.Sprintf("which engine to use (%s)", engineNames()))
+	flag.StringVar(&params.formula, "formula", "mandelbrot", fmt.Sprintf("which formula the fast engine iterates (%s)", formulaNames()))
+	flag.Float64Var(&params.power, "power", 2, "exponent d of the iteration z = z^d + c, any real greater than one (fast/derbail)")
+	flag.BoolVar(&params.julia, "julia", false, "render the Julia set for the constant c given by -cr and -ci")
+	flag.Float64Var(&params.cr, "cr", -0.7, "real part of the Julia constant c")
+	flag.Float64Var(&params.ci, "ci", 0.27015, "imaginary part of the Julia constant c")
+	flag.BoolVar(&params.headless, "headless", false, "render -it iterations without opening a window, then write the image to -out")
+	flag.StringVar(&params.out, "out", "mandelbrot.png", "path of the PNG written in headless mode or by the S key")
+	flag.StringVar(&params.load, "load", "", "start from the view in this location file, flags given explicitly take precedence")
+	flag.StringVar(&params.fromPNG, "from-png", "", "start from the view embedded into an image saved by this program, flags given explicitly take precedence")
+	flag.StringVar(&params.locationOut, "location", "location.json", "path of the location file written by the L key")
+	for _, definition := range engineDefinitions() {
+		if definition.Flags != nil {
+			definition.Flags(flag.CommandLine, &params)
+		}
+	}
 	flag.Parse()
 
+	if params.load != "" || params.fromPNG != "" {
+		var location Location
+		var err error
+		if params.load != "" {
+			location, err = LoadLocation(params.load)
+		} else {
+			location, err = LoadLocationFromPNG(params.fromPNG)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		explicit := map[string]string{}
+		flag.Visit(func(f *flag.Flag) {
+			explicit[f.Name] = f.Value.String()
+		})
+
+		location.applyTo(&params)
+		for name, value := range explicit {
+			flag.Set(name, value)
+		}
+	}
+
+	if params.load == "" && params.fromPNG == "" {
+		// Every formula has its own default view, the flags only hold the
+		// Mandelbrot one. Engines drawing elsewhere than in the plane of c
+		// bring their own.
+		if formula, ok := ParseFormula(params.formula); ok {
+			view := formula.DefaultView()
+			// Multibrots of other powers are symmetric around the origin.
+			if formula == Mandelbrot && params.power != 2 {
+				view.CenterX = "0"
+			}
+			if definition, ok := LookupEngine(params.engine); ok && definition.View != nil {
+				view = *definition.View
+			}
+			if !isFlagSet("x") {
+				params.centerX = view.CenterX
+			}
+			if !isFlagSet("y") {
+				params.centerY = view.CenterY
+			}
+			if !isFlagSet("scale") {
+				params.scale = view.Scale
+			}
+		}
+
+		// Julia sets are centered on the origin, so only keep the default
+		// center when it was asked for explicitly.
+		if params.julia {
+			if !isFlagSet("x") {
+				params.centerX = "0"
+			}
+			if !isFlagSet("y") {
+				params.centerY = "0"
+			}
+		}
+	}
+
 	verify(params)
 
-	a := app.New()
-	w := a.NewWindow("Mandelbrot")
+	title := "Mandelbrot"
+	if params.julia {
+		title = "Julia"
+	}
+
+	var w fyne.Window
+	var viewer *Viewer
+	if !params.headless {
+		a := app.New()
+		w = a.NewWindow(title)
+		viewer = NewViewer(params.width, params.height)
+		w.SetContent(viewer)
+	}
 
 	var width = params.width
 	var height = params.height
@@ -99,38 +404,11 @@ func main() {
 
 	chunkSizeX, chunkSizeY := params.chunkSizeX, params.chunkSizeY
 
-	engineParams := FastFloatEngineParams{
-		Width:   width,
-		Height:  height,
-		CenterX: &params.centerX, //Ptr(0.07318231460617092),
-		CenterY: &params.centerY, //Ptr(0.6137973663828865),
-		// CenterX: Ptr(0.3290059999116987),
-		// CenterY: Ptr(0.5184159787873756),
-		Scale: &params.scale, //Ptr(14586006),
-		// Scale:         Ptr(49259936850),
-		SubIterations: &params.subiterations, //Ptr(500),
-		ChunkSizeX:    &params.chunkSizeX,    //Ptr(chunkSizeX),
-		ChunkSizeY:    &params.chunkSizeY,    //Ptr(chunkSizeY),
-	}
-
-	var sampler Sampler
-	if params.sampler == "linear" {
-		sampler = LinearSampler{
-			n: height / chunkSizeY,
-			m: width / chunkSizeX,
-		}
-	} else if params.sampler == "hilbert" {
-		sampler = UnCachedHilbertCurveSampler{
-			n: height / chunkSizeX,
-			m: width / chunkSizeY,
-		}
-	} else if params.sampler == "cachedhilbert" {
-		sampler = NewHilbertCurveSampler(height, width)
-	}
+	sampler := newSampler(params.sampler, height/chunkSizeY, width/chunkSizeX)
 
 	color_converter := ExponentialMappedModuloColorRangeConverer{
-		S:     1.1,
-		Steps: 20,
+		S:     params.colorExponent,
+		Steps: params.colorSteps,
 	}
 
 	var color_picker ColorOf
@@ -145,8 +423,15 @@ func main() {
 	// iterationStoppedChannel := make(chan bool)
 	iterationContext, iterationContextCancel := context.WithCancel(context.TODO())
 
+	newEngine := func(p cliParams) Engine {
+		definition, _ := LookupEngine(p.engine)
+		return definition.New(p)
+	}
+
 	// engineX := NewFastFloatEngine(engineParams)
-	engineX := NewFastFloatEngine(engineParams)
+	engineX := newEngine(params)
+
+	update_image := imageUpdaterOf(params, engineX)
 
 	// func() {
 	// 	for {
@@ -166,7 +451,29 @@ func main() {
 	// 	}
 	// }()
 
-	iterate := func(engineInstance Engine, iteration int) {
+	// iterating is held by whoever iterates, the loop or the Return key,
+	// so that they take turns on the engine.
+	var iterating sync.Mutex
+
+	// shown is a copy of the image of the last finished iteration, along
+	// with its engine. The window shows it and saving writes it, while the
+	// next iteration paints over the engine's image.
+	type snapshot struct {
+		engine Engine
+		image  *image.RGBA
+	}
+	var shown atomic.Pointer[snapshot]
+
+	// saveShown writes the image of the last finished iteration of engine.
+	saveShown := func(engine Engine) error {
+		last := shown.Load()
+		if last == nil || last.engine != engine {
+			return errors.New("no iteration of the view has finished yet")
+		}
+		return savePNG(params.out, last.image, pngTextOf(params))
+	}
+
+	iterate := func(iterationContext context.Context, engineInstance Engine, iteration int) {
 		if engineInstance.IsStopped() {
 			return
 		}
@@ -175,89 +482,95 @@ func main() {
 		engineInstance.IncreaseIteration()
 		startTime := time.Now()
 
-		workerPool := pond.NewPool(128, pond.WithContext(iterationContext))
-
-		for k := range engineInstance.GetChunkedArea() {
-			P := sampler.Sample(k)
-			i, j := P.x, P.y
-
-			if engineInstance.IsStopped() {
-				return
-			}
-			if engineInstance.CanSkipChunk(i, j) {
-				continue
-			}
-
-			workerPool.Submit(func() {
-				engineInstance.Perform(iterationContext, int32(j), int32(i))
-
-				if engineInstance.IsStopped() {
-					return
-				}
-
-				X := chunkSizeX * int(j)
-				Y := chunkSizeY * int(i)
-
-				for x := 0; x < chunkSizeX; x++ {
-					for y := 0; y < chunkSizeY; y++ {
-						px := X + x
-						py := Y + y
-
-						if engineInstance.IsStopped() {
-							return
-						}
-
-						updateImage(engineInstance.GetImage(), px, py, color_converter, color_picker, engineInstance)
-					}
-				}
-			})
+		performIteration(iterationContext, engineInstance, sampler, chunkSizeX, chunkSizeY, func(px, py int) {
+			update_image(engineInstance.GetImage(), px, py, color_converter, color_picker, engineInstance)
+		})
+		if engineInstance.IsStopped() {
+			return
 		}
-		workerPool.StopAndWait()
 
 		for j := range width {
 			for i := range height {
 				px := j
 				py := i
 
-				updateImage(engineInstance.GetImage(), px, py, color_converter, color_picker, engineInstance)
+				update_image(engineInstance.GetImage(), px, py, color_converter, color_picker, engineInstance)
 			}
 		}
 
 		endTime := time.Now()
 		duration := endTime.Sub(startTime).Milliseconds()
 		totalTime += int(duration)
-		metric := math.Round(float64(1000*totalTime) / float64(engineInstance.GetIterations()))
-		w.SetTitle("Mandelbrot: [" + fmt.Sprint(width, "x", height) + "] " + fmt.Sprint(engineInstance.GetIterations()) + " iterations (" + fmt.Sprint(metric) + "ms / 1000 iterations)")
 
-		img := canvas.NewImageFromImage(engineInstance.GetImage())
-		w.SetContent(img)
+		last := &snapshot{engine: engineInstance, image: cloneRGBA(engineInstance.GetImage())}
+		shown.Store(last)
+
+		if w != nil {
+			metric := math.Round(float64(1000*totalTime) / float64(engineInstance.GetIterations()))
+			w.SetTitle(title + ": [" + fmt.Sprint(width, "x", height) + "] " + fmt.Sprint(engineInstance.GetIterations()) + " iterations (" + fmt.Sprint(metric) + "ms / 1000 iterations)")
+
+			viewer.SetImage(last.image)
+		}
 
 		fmt.Println("Iteration", iteration, "completed successfully in ", duration, " ms")
 	}
 
-	iterationLoop := func(engine Engine) {
+	iterationLoop := func(iterationContext context.Context, engine Engine) {
 		for iterations := range params.iterations {
 			select {
 			case <-iterationContext.Done():
 				return
 
 			default:
-				iterate(engine, iterations)
+				iterating.Lock()
+				iterate(iterationContext, engine, iterations)
+				iterating.Unlock()
 			}
 		}
 
+		iterating.Lock()
 		fmt.Println("All iterations completed in ", totalTime, " ms")
+		iterating.Unlock()
 	}
-	go iterationLoop(engineX)
+	if params.headless {
+		iterationLoop(iterationContext, engineX)
+
+		if err := saveShown(engineX); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	go iterationLoop(iterationContext, engineX)
+
+	// running holds the parameters of the running engine, which the view
+	// returns to when a change takes it out of the range the engine renders.
+	running := params
+	resetWith := func(newParams cliParams) {
+		if definition, _ := LookupEngine(newParams.engine); !definition.DeepZoom {
+			if _, err := newParams.viewport(); err != nil {
+				log.Printf("%v, staying at scale %s", err, running.scale)
+				params = running
+				return
+			}
+		}
+		running = newParams
 
-	resetWith := func(newParams FastFloatEngineParams) {
 		iterationContextCancel()
 		engineX.Stop()
 
 		iterationContext, iterationContextCancel = context.WithCancel(context.TODO())
-		engineX = NewFastFloatEngine(newParams)
-		go iterationLoop(engineX)
+		engineX = newEngine(newParams)
+		go iterationLoop(iterationContext, engineX)
+	}
+
+	// panBy moves the view by (dx, dy) pixels along the axes of the screen,
+	// which differ from those of the plane once the view is rotated.
+	panBy := func(dx, dy float64) {
+		params.centerX, params.centerY = params.offsetCenter(float64(width/2)+dx, float64(height/2)+dy, 1)
+		resetWith(params)
 	}
+	// The arrow keys move the view by 1/300 of its width, 0.01 at scale 1.
+	keyPanStep := float64(width) / 300
 
 	w.Canvas().SetOnTypedKey(func(ke *fyne.KeyEvent) {
 		switch ke.Name {
@@ -266,55 +579,91 @@ func main() {
 			return
 
 		case fyne.KeyS:
-			im := engineX.GetImage()
-
-			f, err := os.Create("mandelbrot.png")
-			if err != nil {
+			if err := saveShown(engineX); err != nil {
 				log.Println(err)
-				return
 			}
 
-			if err = png.Encode(f, im); err != nil {
+		case fyne.KeyL:
+			if err := SaveLocation(params.locationOut, locationOf(params)); err != nil {
 				log.Println(err)
 			}
-			f.Close()
 
 		case fyne.KeyReturn:
-			iterate(engineX, engineX.GetIterations()+1)
+			go func(iterationContext context.Context, engine Engine) {
+				iterating.Lock()
+				defer iterating.Unlock()
+
+				iterate(iterationContext, engine, engine.GetIterations()+1)
+			}(iterationContext, engineX)
 
 		case fyne.KeyR:
-			resetWith(engineParams)
+			resetWith(params)
 
 		case fyne.KeyPlus:
-			*engineParams.Scale++
-			resetWith(engineParams)
+			params.scale = ScaleDecimal(params.scale, zoomStep)
+			resetWith(params)
 
 		case fyne.KeyMinus:
-			*engineParams.Scale--
-			resetWith(engineParams)
+			params.scale = ScaleDecimal(params.scale, 1/zoomStep)
+			resetWith(params)
+
+		case fyne.KeyLeftBracket:
+			params.angle = math.Mod(params.angle+rotateStep, 360)
+			resetWith(params)
+
+		case fyne.KeyRightBracket:
+			params.angle = math.Mod(params.angle-rotateStep, 360)
+			resetWith(params)
 
 		case fyne.KeyLeft:
-			delta := 0.01 / float64(*engineParams.Scale)
-			engineParams.CenterX = Ptr(*engineParams.CenterX - delta)
-			resetWith(engineParams)
+			panBy(-keyPanStep, 0)
 
 		case fyne.KeyRight:
-			delta := 0.01 / float64(*engineParams.Scale)
-			engineParams.CenterX = Ptr(*engineParams.CenterX + delta)
-			resetWith(engineParams)
+			panBy(keyPanStep, 0)
 
 		case fyne.KeyUp:
-			delta := 0.01 / float64(*engineParams.Scale)
-			engineParams.CenterY = Ptr(*engineParams.CenterY - delta)
-			resetWith(engineParams)
+			panBy(0, -keyPanStep)
 
 		case fyne.KeyDown:
-			delta := 0.01 / float64(*engineParams.Scale)
-			engineParams.CenterY = Ptr(*engineParams.CenterY + delta)
-			resetWith(engineParams)
+			panBy(0, keyPanStep)
 		}
 
 	})
+	viewer.OnTapped = func(px, py float64) {
+		params.centerX, params.centerY = params.offsetCenter(px, py, 1)
+		resetWith(params)
+	}
+
+	viewer.OnScrolled = func(px, py float64, up bool) {
+		factor := zoomStep
+		if !up {
+			factor = 1 / zoomStep
+		}
+
+		// Keep the point under the cursor where it is.
+		params.centerX, params.centerY = params.offsetCenter(px, py, 1-1/factor)
+		params.scale = ScaleDecimal(params.scale, factor)
+		resetWith(params)
+	}
+
+	viewer.OnPanned = func(dx, dy float64) {
+		panBy(-dx, -dy)
+	}
+
+	viewer.OnRotated = func(degrees float64) {
+		params.angle = math.Mod(params.angle+degrees, 360)
+		resetWith(params)
+	}
+
+	viewer.OnSelected = func(x0, y0, x1, y1 float64) {
+		params.centerX, params.centerY = params.offsetCenter((x0+x1)/2, (y0+y1)/2, 1)
+
+		// Zoom so that the selection fills the view.
+		zoom := min(float64(width)/math.Abs(x1-x0), float64(height)/math.Abs(y1-y0))
+		params.scale = ScaleDecimal(params.scale, max(zoom, 1))
+		resetWith(params)
+	}
+
 	w.Resize(fyne.NewSize(float32(width), float32(height)))
 	w.ShowAndRun()
 }
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image/color"
+	"math"
+	"math/cmplx"
+)
+
+// newtonTolerance is how short a Newton step has to get for the orbit to
+// count as converged. Near a root of multiplicity m the step is 1/m of the
+// distance to it, which shrinks only linearly, so the step is what reaches a
+// tolerance even where the float64 distance to a repeated root cannot.
+const newtonTolerance = 1e-6
+
+// NewtonEngine iterates Newton's method z = z - p(z)/p'(z) from every pixel
+// and records which root of p it converges to, and after how many steps.
+type NewtonEngine struct {
+	*engineCore
+
+	z     []complex128
+	steps []int
+	root  []int
+
+	polynomial Polynomial
+	roots      []complex128
+}
+
+type NewtonEngineParams struct {
+	EngineParams
+	Polynomial Polynomial
+}
+
+func init() {
+	RegisterEngine(EngineDefinition{
+		Name: "newton",
+		Flags: func(flags *flag.FlagSet, params *cliParams) {
+			flags.StringVar(&params.poly, "poly", "z^3-1", "polynomial in z whose roots the newton engine finds, e.g. \"z^4 - 2.5i*z + 1\"")
+		},
+		Verify: func(params cliParams) error {
+			polynomial, err := ParsePolynomial(params.poly)
+			if err != nil {
+				return fmt.Errorf("invalid polynomial: %w", err)
+			}
+			if polynomial.Degree() < 2 {
+				return fmt.Errorf("the polynomial %s must be of degree two or more", params.poly)
+			}
+			for _, root := range polynomial.Roots() {
+				if cmplx.IsNaN(root) || cmplx.IsInf(root) {
+					return fmt.Errorf("could not find the roots of the polynomial %s", params.poly)
+				}
+			}
+			return nil
+		},
+		// Newton basins are centered on the origin.
+		View: &FormulaView{CenterX: "0", CenterY: "0", Scale: "1"},
+		New: func(params cliParams) Engine {
+			polynomial, _ := ParsePolynomial(params.poly)
+			return NewNewtonEngine(NewtonEngineParams{
+				EngineParams: params.engineParams(),
+				Polynomial:   polynomial,
+			})
+		},
+	})
+}
+
+func NewNewtonEngine(params NewtonEngineParams) *NewtonEngine {
+	engine := NewtonEngine{
+		engineCore: newEngineCore(params.EngineParams),
+		polynomial: params.Polynomial,
+		roots:      distinctRoots(params.Polynomial.Roots()),
+	}
+	engine.z = newPixels[complex128](&engine.chunkGrid)
+	engine.steps = newPixels[int](&engine.chunkGrid)
+	engine.root = newPixels[int](&engine.chunkGrid)
+
+	engine.forEachPixel(func(p int, px, py int32) {
+		engine.z[p] = complex(engine.toPlane(px, py))
+	})
+
+	return &engine
+}
+
+// distinctRoots keeps one of every group of roots closer than
+// newtonTolerance, the copies of a repeated root, so that its basin gets a
+// single color.
+func distinctRoots(roots []complex128) []complex128 {
+	var distinct []complex128
+	for _, root := range roots {
+		repeated := false
+		for _, other := range distinct {
+			if cmplx.Abs(root-other) < newtonTolerance {
+				repeated = true
+				break
+			}
+		}
+		if !repeated {
+			distinct = append(distinct, root)
+		}
+	}
+	return distinct
+}
+
+// nearestRoot returns the index of the root closest to z.
+func (f *NewtonEngine) nearestRoot(z complex128) int {
+	nearest := 0
+	for k, root := range f.roots {
+		if cmplx.Abs(z-root) < cmplx.Abs(z-f.roots[nearest]) {
+			nearest = k
+		}
+	}
+	return nearest
+}
+
+func (f *NewtonEngine) Perform(context context.Context, x, y int32) {
+	f.performChunk(context, x, y, func(p int, px, py int32) {
+		z := f.z[p]
+		for range f.subIterations {
+			value, derivative := f.polynomial.Eval(z)
+			if value != 0 && derivative == 0 {
+				// Newton's method is undefined at critical points.
+				f.explodesAt[p] = -1
+				break
+			}
+
+			// Count the step, so that a pixel on a root still reads as
+			// converged after one.
+			step := complex(0, 0)
+			if value != 0 {
+				step = value / derivative
+			}
+			z -= step
+			f.steps[p]++
+
+			if cmplx.Abs(step) < newtonTolerance {
+				f.root[p] = f.nearestRoot(z)
+				f.explodesAt[p] = f.steps[p]
+				break
+			}
+		}
+		f.z[p] = z
+	})
+}
+
+func (f *NewtonEngine) GetSmoothExplodesAt(x, y int32) float64 {
+	return float64(f.GetExplodesAt(x, y))
+}
+
+// GetColor colors the basin of every root with its own color from the
+// palette, darker the longer the pixel took to converge.
+func (f *NewtonEngine) GetColor(x, y int32, colorPicker ColorOf) color.RGBA {
+	steps := f.GetExplodesAt(x, y)
+	if steps <= 0 {
+		return color.RGBA{A: 255}
+	}
+
+	p := f.pixelIndex(x, y)
+
+	base := colorPicker.Get((float64(f.root[p]) + 0.5) / float64(len(f.roots)))
+	shade := 1 - 0.8*math.Log1p(float64(steps))/math.Log1p(float64(f.GetMaxExplodesAt()))
+
+	return color.RGBA{
+		R: uint8(float64(base.R) * shade),
+		G: uint8(float64(base.G) * shade),
+		B: uint8(float64(base.B) * shade),
+		A: 255,
+	}
+}
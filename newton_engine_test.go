@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+// TestNewtonConvergesToRepeatedRoots checks that every pixel converges, to a
+// root it lies in the basin of, also where Newton's method slows down to
+// linear convergence at a repeated root.
+func TestNewtonConvergesToRepeatedRoots(t *testing.T) {
+	for _, test := range []struct {
+		polynomial string
+		roots      []complex128
+	}{
+		{"z^3-1", []complex128{1, -0.5 + 0.8660254037844386i, -0.5 - 0.8660254037844386i}},
+		{"z^3-3z^2+3z-1", []complex128{1}},
+		{"z^3-3z+2", []complex128{1, -2}},
+	} {
+		t.Run(test.polynomial, func(t *testing.T) {
+			params := testParams("newton", 32, 32)
+			params.centerX, params.poly = "0", test.polynomial
+			params.subiterations = 200
+
+			engine := render(params, 1).(*NewtonEngine)
+			if len(engine.roots) != len(test.roots) {
+				t.Fatalf("found roots %v, want %v", engine.roots, test.roots)
+			}
+
+			for y := range int32(params.height) {
+				for x := range int32(params.width) {
+					if engine.GetExplodesAt(x, y) < 0 {
+						// The orbit hit a critical point.
+						continue
+					}
+					if engine.GetExplodesAt(x, y) == 0 {
+						t.Fatalf("pixel (%d, %d) did not converge", x, y)
+					}
+
+					p := engine.pixelIndex(x, y)
+					root := engine.roots[engine.root[p]]
+					if cmplx.Abs(engine.z[p]-root) > 1e-4 {
+						t.Fatalf("pixel (%d, %d) converged to %v, assigned to %v", x, y, engine.z[p], root)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"math"
+)
+
+// PerturbationEngine iterates a single reference orbit at the center in
+// arbitrary precision and every pixel as a float64 delta δ from it:
+//
+//	δₙ₊₁ = 2Zₙδₙ + δₙ² + δc
+//
+// A pixel is glitched when |Zₘ + δ| < |δ|, i.e. its orbit passes closer to
+// zero than to the reference, and then loses all its precision in δ. Such
+// pixels, as well as those outliving an escaped reference, are re-referenced
+// to the start of the orbit with δ = z - Z₀, m = 0, which keeps them exact.
+type PerturbationEngine struct {
+	*engineCore
+
+	dzr      []float64
+	dzi      []float64
+	refIndex []int
+
+	precision int
+
+	// orbit holds the reference Zₙ rounded to float64, up to and including
+	// the first escaping one, and reference the last of them in full.
+	orbit      []complex128
+	reference  AComplex
+	refC       AComplex
+	refEscaped bool
+
+	// seriesApproximation skips the iterations a series approximates for
+	// the whole view, once, before the first round of Perform calls.
+	seriesApproximation bool
+	seriesApplied       bool
+	skippedIterations   int
+}
+
+type PerturbationEngineParams struct {
+	EngineParams
+	// The view in decimal, which takes the place of the float64 CenterX,
+	// CenterY and Scale beyond their precision and range.
+	DecimalCenterX, DecimalCenterY *string
+	DecimalScale                   *string
+	SeriesApproximation            *bool
+}
+
+func init() {
+	RegisterEngine(EngineDefinition{
+		Name: "perturbation",
+		Flags: func(flags *flag.FlagSet, params *cliParams) {
+			flags.BoolVar(&params.seriesApproximation, "sa", false, "skip early iterations with a series approximation (perturbation engine)")
+		},
+		Julia: true,
+		New: func(params cliParams) Engine {
+			return NewPerturbationEngine(PerturbationEngineParams{
+				EngineParams:        params.engineParamsWithoutView(),
+				DecimalCenterX:      &params.centerX,
+				DecimalCenterY:      &params.centerY,
+				DecimalScale:        &params.scale,
+				SeriesApproximation: &params.seriesApproximation,
+			})
+		},
+	})
+}
+
+func NewPerturbationEngine(params PerturbationEngineParams) *PerturbationEngine {
+	scale := parseScale(Elvis(params.DecimalScale, "1"))
+	precision := precisionForScale(params.Width, scale)
+
+	center, ok := NewFromString(Elvis(params.DecimalCenterX, "-0.75"), Elvis(params.DecimalCenterY, "0"), precision)
+	if !ok {
+		center = New(-0.75, 0)
+	}
+
+	// Pixel deltas are float64, which caps the zoom at around 1e300.
+	floatScale, _ := scale.Float64()
+
+	centerX, centerY := real(Complex128(*center)), imag(Complex128(*center))
+
+	core := params.EngineParams
+	core.CenterX, core.CenterY, core.Scale = &centerX, &centerY, &floatScale
+
+	engine := PerturbationEngine{
+		engineCore:          newEngineCore(core),
+		precision:           precision,
+		seriesApproximation: Elvis(params.SeriesApproximation, false),
+	}
+	engine.dzr = newPixels[float64](&engine.chunkGrid)
+	engine.dzi = newPixels[float64](&engine.chunkGrid)
+	engine.refIndex = newPixels[int](&engine.chunkGrid)
+
+	// Mandelbrot: Z₀ = 0 and C is the center. Julia: Z₀ is the center and
+	// C the constant, while every pixel starts off with δ₀ = its offset.
+	if engine.julia {
+		engine.reference = *center
+		engine.refC = *New(Elvis(params.CReal, 0), Elvis(params.CImag, 0))
+		engine.seedJulia(func(p int, px, py int32) {
+			engine.dzr[p], engine.dzi[p] = engine.viewport.Offset(float64(px), float64(py))
+		})
+	} else {
+		engine.reference = AComplex{}
+		engine.reference.r.Context.Precision = precision
+		engine.reference.i.Context.Precision = precision
+		engine.refC = *center
+	}
+	engine.orbit = append(engine.orbit, Complex128(engine.reference))
+	engine.extendOrbit(engine.iterations + engine.subIterations)
+
+	return &engine
+}
+
+// extendOrbit iterates the reference in full precision until it holds n+1
+// values or escapes.
+func (f *PerturbationEngine) extendOrbit(n int) {
+	for len(f.orbit) <= n && !f.refEscaped {
+		f.reference = Add(Mul(f.reference, f.reference), f.refC)
+		f.orbit = append(f.orbit, Complex128(f.reference))
+		f.refEscaped = GtR(f.reference, f.escapeRadius)
+	}
+}
+
+func (f *PerturbationEngine) Perform(context context.Context, x, y int32) {
+	orbit := f.orbit
+	last := len(orbit) - 1
+
+	escapeRadius2 := f.escapeRadius * f.escapeRadius
+
+	f.performChunk(context, x, y, func(p int, px, py int32) {
+		dcr, dci := 0.0, 0.0
+		if !f.julia {
+			dcr, dci = f.viewport.Offset(float64(px), float64(py))
+		}
+
+		dr, di := f.dzr[p], f.dzi[p]
+		m := f.refIndex[p]
+		for i := range f.subIterations {
+			zr := real(orbit[m]) + dr
+			zi := imag(orbit[m]) + di
+			z2 := zr*zr + zi*zi
+
+			if z2 > escapeRadius2 {
+				f.escape(p, i, math.Sqrt(z2))
+				break
+			}
+
+			if z2 < dr*dr+di*di || m == last {
+				dr, di = zr-real(orbit[0]), zi-imag(orbit[0])
+				m = 0
+			}
+
+			Zr, Zi := real(orbit[m]), imag(orbit[m])
+			dr, di = 2*(Zr*dr-Zi*di)+dr*dr-di*di+dcr, 2*(Zr*di+Zi*dr+dr*di)+dci
+			m++
+		}
+		f.dzr[p], f.dzi[p] = dr, di
+		f.refIndex[p] = m
+	})
+}
+
+// IncreaseIteration also grows the reference orbit far enough for the next
+// round of Perform calls, before they run concurrently.
+func (f *PerturbationEngine) IncreaseIteration() {
+	if f.seriesApproximation && !f.seriesApplied {
+		f.seriesApplied = true
+		f.skipBySeries()
+	}
+
+	f.iterations += f.subIterations
+	f.extendOrbit(f.iterations + f.subIterations)
+}
+
+// GetSkippedIterations returns how many iterations the series approximation
+// skipped for every pixel.
+func (f *PerturbationEngine) GetSkippedIterations() int {
+	return f.skippedIterations
+}
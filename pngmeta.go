@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"slices"
+	"strings"
+)
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// locationKeyword is the keyword of the text chunk holding the image's
+// Location as JSON.
+const locationKeyword = "go-julia location"
+
+// maxPNGChunkLength is the largest chunk length the PNG specification allows.
+const maxPNGChunkLength = 1<<31 - 1
+
+// maxPNGTextLength bounds the text chunks ReadPNGText reads, far above any
+// text this program writes.
+const maxPNGTextLength = 1 << 20
+
+// EncodePNGWithText encodes img as a PNG carrying text right after its
+// header, as tEXt chunks for ASCII values and as iTXt chunks, which hold
+// UTF-8, for the others. tEXt holds Latin-1, whose upper half UTF-8 spells
+// differently.
+func EncodePNGWithText(w io.Writer, img image.Image, text map[string]string) error {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		return err
+	}
+
+	// The signature is followed by IHDR: length, type, 13 bytes of data, CRC.
+	data := encoded.Bytes()
+	headerEnd := len(pngSignature) + 4 + 4 + 13 + 4
+
+	if _, err := w.Write(data[:headerEnd]); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(text))
+	for key := range text {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		var err error
+		if isASCII(text[key]) {
+			err = writePNGChunk(w, "tEXt", []byte(key+"\x00"+text[key]))
+		} else {
+			// Uncompressed, with empty language tag and translated keyword.
+			err = writePNGChunk(w, "iTXt", []byte(key+"\x00\x00\x00\x00\x00"+text[key]))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := w.Write(data[headerEnd:])
+	return err
+}
+
+// ReadPNGText returns the uncompressed tEXt and iTXt chunks of a PNG, with
+// the Latin-1 of tEXt chunks converted to UTF-8.
+func ReadPNGText(r io.Reader) (map[string]string, error) {
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, signature); err != nil {
+		return nil, err
+	}
+	if string(signature) != pngSignature {
+		return nil, errors.New("not a PNG file")
+	}
+
+	text := map[string]string{}
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, err
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		kind := string(header[4:])
+		if length > maxPNGChunkLength {
+			return nil, fmt.Errorf("PNG chunk %q of length %d", kind, length)
+		}
+
+		// Skip the chunks other than text along with their CRC.
+		if kind != "tEXt" && kind != "iTXt" {
+			if _, err := io.CopyN(io.Discard, r, int64(length)+4); err != nil {
+				return nil, err
+			}
+			if kind == "IEND" {
+				return text, nil
+			}
+			continue
+		}
+		if length > maxPNGTextLength {
+			return nil, fmt.Errorf("PNG %s chunk of length %d", kind, length)
+		}
+
+		data := make([]byte, length+4)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		data = data[:length]
+
+		switch kind {
+		case "tEXt":
+			if key, value, ok := strings.Cut(string(data), "\x00"); ok {
+				text[fromLatin1(key)] = fromLatin1(value)
+			}
+
+		case "iTXt":
+			key, rest, ok := strings.Cut(string(data), "\x00")
+			if !ok || len(rest) < 2 || rest[0] != 0 {
+				continue
+			}
+			// Skip the compression method, language tag and translated keyword.
+			_, rest, _ = strings.Cut(rest[2:], "\x00")
+			_, value, ok := strings.Cut(rest, "\x00")
+			if ok {
+				text[key] = value
+			}
+		}
+	}
+}
+
+func writePNGChunk(w io.Writer, kind string, data []byte) error {
+	chunk := make([]byte, 0, 12+len(data))
+	chunk = binary.BigEndian.AppendUint32(chunk, uint32(len(data)))
+	chunk = append(chunk, kind...)
+	chunk = append(chunk, data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	_, err := w.Write(chunk)
+	return err
+}
+
+func isASCII(s string) bool {
+	for i := range len(s) {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// fromLatin1 returns the UTF-8 of the Latin-1 bytes s.
+func fromLatin1(s string) string {
+	if isASCII(s) {
+		return s
+	}
+	runes := make([]rune, len(s))
+	for i := range len(s) {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
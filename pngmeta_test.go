@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestPNGTextRoundTrip(t *testing.T) {
+	text := map[string]string{
+		"ascii":   "-0.75, 0",
+		"latin-1": "café ×2",
+		"utf-8":   "曼德博集合",
+	}
+
+	var encoded bytes.Buffer
+	if err := EncodePNGWithText(&encoded, image.NewRGBA(image.Rect(0, 0, 4, 4)), text); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only ASCII goes into tEXt, which holds Latin-1 rather than UTF-8.
+	if !bytes.Contains(encoded.Bytes(), []byte("tEXtascii\x00")) {
+		t.Error("ASCII text not in a tEXt chunk")
+	}
+	for _, key := range []string{"latin-1", "utf-8"} {
+		if !bytes.Contains(encoded.Bytes(), []byte("iTXt"+key+"\x00")) {
+			t.Errorf("%s text not in an iTXt chunk", key)
+		}
+	}
+
+	got, err := ReadPNGText(&encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range text {
+		if got[key] != want {
+			t.Errorf("text %s = %q, want %q", key, got[key], want)
+		}
+	}
+}
+
+// pngWithChunk returns a PNG signature followed by a chunk of kind claiming
+// length, with data as far as it goes, and an IEND chunk.
+func pngWithChunk(kind string, length uint32, data []byte) []byte {
+	png := []byte(pngSignature)
+	png = binary.BigEndian.AppendUint32(png, length)
+	png = append(png, kind...)
+	png = append(png, data...)
+	png = append(png, 0, 0, 0, 0)
+	png = binary.BigEndian.AppendUint32(png, 0)
+	return append(png, "IEND\x00\x00\x00\x00"...)
+}
+
+func TestReadPNGTextLatin1(t *testing.T) {
+	data := []byte("key\x00caf\xe9")
+	text, err := ReadPNGText(bytes.NewReader(pngWithChunk("tEXt", uint32(len(data)), data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := text["key"], "café"; got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+}
+
+func TestReadPNGTextChunkLength(t *testing.T) {
+	for _, test := range []struct {
+		kind   string
+		length uint32
+	}{
+		{"tEXt", 1 << 31},
+		{"tEXt", 0xffffffff},
+		{"iTXt", maxPNGTextLength + 1},
+		{"IDAT", 1 << 31},
+	} {
+		_, err := ReadPNGText(bytes.NewReader(pngWithChunk(test.kind, test.length, nil)))
+		if err == nil || !strings.Contains(err.Error(), "length") {
+			t.Errorf("%s chunk of length %d: error %v", test.kind, test.length, err)
+		}
+	}
+}
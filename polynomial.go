@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+)
+
+// Polynomial holds complex coefficients, the one of z^k at index k.
+type Polynomial []complex128
+
+// ParsePolynomial reads a polynomial in z such as "z^3-1" or "2z^4 - 3.5i*z + 1".
+// Coefficients are real or imaginary numbers, terms may repeat a power.
+func ParsePolynomial(text string) (Polynomial, error) {
+	s := strings.ReplaceAll(text, " ", "")
+	if s == "" {
+		return nil, fmt.Errorf("empty polynomial")
+	}
+
+	var p Polynomial
+	for _, term := range splitTerms(s) {
+		sign := complex(1, 0)
+		if rest, ok := strings.CutPrefix(term, "-"); ok {
+			sign, term = -1, rest
+		} else {
+			term = strings.TrimPrefix(term, "+")
+		}
+
+		coefficient, power, err := parseTerm(term)
+		if err != nil {
+			return nil, fmt.Errorf("%v in %q", err, text)
+		}
+		for len(p) <= power {
+			p = append(p, 0)
+		}
+		p[power] += sign * coefficient
+	}
+
+	return p.trimmed(), nil
+}
+
+// splitTerms cuts s before every sign that is not part of an exponent, as in
+// 1e-3 or z^-1.
+func splitTerms(s string) []string {
+	var terms []string
+	start := 0
+	for i := 1; i < len(s); i++ {
+		if (s[i] == '+' || s[i] == '-') && s[i-1] != 'e' && s[i-1] != 'E' && s[i-1] != '^' {
+			terms = append(terms, s[start:i])
+			start = i
+		}
+	}
+	return append(terms, s[start:])
+}
+
+// parseTerm reads one term such as "3", "2.5i", "z", "4z^2" or "i*z^3"
+// without its sign.
+func parseTerm(term string) (complex128, int, error) {
+	if term == "" {
+		return 0, 0, fmt.Errorf("missing term")
+	}
+
+	coefficientText, powerText, hasZ := strings.Cut(term, "z")
+	coefficientText = strings.TrimSuffix(coefficientText, "*")
+
+	coefficient := complex(1, 0)
+	if imaginary := strings.HasSuffix(coefficientText, "i"); imaginary {
+		coefficientText = strings.TrimSuffix(coefficientText, "i")
+		coefficient = complex(0, 1)
+	}
+	if coefficientText != "" {
+		value, err := strconv.ParseFloat(coefficientText, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid coefficient %q", coefficientText)
+		}
+		coefficient *= complex(value, 0)
+	} else if !hasZ && coefficient == 1 {
+		return 0, 0, fmt.Errorf("invalid term %q", term)
+	}
+
+	if !hasZ {
+		return coefficient, 0, nil
+	}
+	if powerText == "" {
+		return coefficient, 1, nil
+	}
+
+	exponent, ok := strings.CutPrefix(powerText, "^")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid term %q", term)
+	}
+	power, err := strconv.Atoi(exponent)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid power %q", exponent)
+	}
+	if power < 0 {
+		return 0, 0, fmt.Errorf("negative power %d, only polynomials in z are supported", power)
+	}
+	return coefficient, power, nil
+}
+
+// trimmed drops the zero coefficients of the highest powers.
+func (p Polynomial) trimmed() Polynomial {
+	for len(p) > 0 && p[len(p)-1] == 0 {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+func (p Polynomial) Degree() int {
+	return len(p) - 1
+}
+
+// Eval returns p(z) and p'(z) by Horner's scheme.
+func (p Polynomial) Eval(z complex128) (complex128, complex128) {
+	var value, derivative complex128
+	for k := len(p) - 1; k >= 0; k-- {
+		derivative = derivative*z + value
+		value = value*z + p[k]
+	}
+	return value, derivative
+}
+
+// rootSeparation is how close two guesses of Roots may get before they count
+// as apart by that much. Guesses for a repeated root close in on each other
+// and would otherwise divide by zero.
+const rootSeparation = 1e-150
+
+// Roots finds all roots of p with the Durand-Kerner method, which refines
+// guesses for every root at once. A repeated root comes up as often as it
+// repeats, to about the root of the float64 precision in its multiplicity.
+func (p Polynomial) Roots() []complex128 {
+	n := p.Degree()
+	if n < 1 {
+		return nil
+	}
+
+	lead := p[n]
+	monic := func(z complex128) complex128 {
+		value, _ := p.Eval(z)
+		return value / lead
+	}
+
+	roots := make([]complex128, n)
+	for k := range roots {
+		roots[k] = cmplx.Pow(complex(0.4, 0.9), complex(float64(k), 0))
+	}
+
+	for range 1000 {
+		change := 0.0
+		for k := range roots {
+			denominator := complex(1, 0)
+			for j := range roots {
+				if j != k {
+					difference := roots[k] - roots[j]
+					if cmplx.Abs(difference) < rootSeparation {
+						difference = rootSeparation
+					}
+					denominator *= difference
+				}
+			}
+
+			step := monic(roots[k]) / denominator
+			roots[k] -= step
+			change = math.Max(change, cmplx.Abs(step))
+		}
+
+		if change < 1e-14 {
+			break
+		}
+	}
+
+	return roots
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+// TestPolynomialRoots checks that Roots finds every root as often as it
+// repeats, a repeated one to about the root of the float64 precision in its
+// multiplicity.
+func TestPolynomialRoots(t *testing.T) {
+	for _, test := range []struct {
+		polynomial string
+		roots      []complex128
+		tolerance  float64
+	}{
+		{"z^2-1", []complex128{1, -1}, 1e-12},
+		{"z^2+1", []complex128{1i, -1i}, 1e-12},
+		{"z^3-1", []complex128{1, -0.5 + 0.8660254037844386i, -0.5 - 0.8660254037844386i}, 1e-12},
+		{"z^4 - 2.5i*z + 1", nil, 1e-12},
+		{"z^2-2z+1", []complex128{1, 1}, 1e-6},
+		{"z^3-3z+2", []complex128{1, 1, -2}, 1e-6},
+		{"z^3-3z^2+3z-1", []complex128{1, 1, 1}, 1e-4},
+		{"z^4-2z^2+1", []complex128{1, 1, -1, -1}, 1e-6},
+		{"z^5", []complex128{0, 0, 0, 0, 0}, 1e-2},
+	} {
+		t.Run(test.polynomial, func(t *testing.T) {
+			polynomial, err := ParsePolynomial(test.polynomial)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			roots := polynomial.Roots()
+			if len(roots) != polynomial.Degree() {
+				t.Fatalf("found %d roots %v, want %d", len(roots), roots, polynomial.Degree())
+			}
+			for _, root := range roots {
+				if cmplx.IsNaN(root) || cmplx.IsInf(root) {
+					t.Fatalf("found root %v", root)
+				}
+				if value, _ := polynomial.Eval(root); cmplx.Abs(value) > test.tolerance {
+					t.Errorf("p(%v) = %v, want 0", root, value)
+				}
+			}
+
+			// Match every expected root with a found one of its own.
+			unmatched := append([]complex128(nil), roots...)
+			for _, want := range test.roots {
+				match := -1
+				for k, root := range unmatched {
+					if cmplx.Abs(root-want) < test.tolerance && (match < 0 || cmplx.Abs(root-want) < cmplx.Abs(unmatched[match]-want)) {
+						match = k
+					}
+				}
+				if match < 0 {
+					t.Fatalf("no root near %v in %v", want, roots)
+				}
+				unmatched = append(unmatched[:match], unmatched[match+1:]...)
+			}
+		})
+	}
+}
+
+// TestParsePolynomialErrors checks that a negative power reads as one rather
+// than as a term cut at its sign.
+func TestParsePolynomialErrors(t *testing.T) {
+	for _, test := range []struct {
+		polynomial string
+		err        string
+	}{
+		{"z^-1", `negative power -1, only polynomials in z are supported in "z^-1"`},
+		{"z^2+z^-3+1", `negative power -3, only polynomials in z are supported in "z^2+z^-3+1"`},
+		{"z^x", `invalid power "x" in "z^x"`},
+		{"z^2+", `missing term in "z^2+"`},
+	} {
+		t.Run(test.polynomial, func(t *testing.T) {
+			_, err := ParsePolynomial(test.polynomial)
+			if err == nil || err.Error() != test.err {
+				t.Errorf("got error %v, want %s", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "math/cmplx"
+
+// pow returns z^d, multiplying out the small integer powers instead of going
+// through the much slower polar form of cmplx.Pow.
+func pow(z complex128, d float64) complex128 {
+	switch d {
+	case 1:
+		return z
+	case 2:
+		return z * z
+	case 3:
+		return z * z * z
+	case 4:
+		z2 := z * z
+		return z2 * z2
+	case 5:
+		z2 := z * z
+		return z2 * z2 * z
+	}
+	return cmplx.Pow(z, complex(d, 0))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// EngineDefinition describes an engine that can be chosen with -engine.
+type EngineDefinition struct {
+	Name string
+	// Flags registers the flags only this engine reads into flags, along with
+	// their defaults.
+	Flags func(flags *flag.FlagSet, params *cliParams)
+	// Verify reports what is wrong with the engine's parameters, if anything.
+	Verify func(params cliParams) error
+	// View replaces the formula's default view when the engine does not draw
+	// in the plane of c.
+	View *FormulaView
+
+	// Julia, Formulas, Powers and Distance tell whether the engine supports
+	// -julia, formulas other than the Mandelbrot, -power and -render distance.
+	Julia, Formulas, Powers, Distance bool
+	// DeepZoom tells whether the engine reads the center and scale as
+	// decimals, at zooms beyond the range of a float64.
+	DeepZoom bool
+
+	New func(params cliParams) Engine
+}
+
+var engines = map[string]EngineDefinition{}
+
+// RegisterEngine makes an engine available to -engine, engines register
+// themselves from the init function of their file.
+func RegisterEngine(definition EngineDefinition) {
+	if _, ok := engines[definition.Name]; ok {
+		panic(fmt.Sprintf("engine %s registered twice", definition.Name))
+	}
+	engines[definition.Name] = definition
+}
+
+func LookupEngine(name string) (EngineDefinition, bool) {
+	definition, ok := engines[name]
+	return definition, ok
+}
+
+// flagNames lists the flags only the engine reads.
+func (d EngineDefinition) flagNames() []string {
+	if d.Flags == nil {
+		return nil
+	}
+	flags := flag.NewFlagSet(d.Name, flag.ContinueOnError)
+	d.Flags(flags, &cliParams{})
+
+	var names []string
+	flags.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	return names
+}
+
+// engineDefinitions returns the registered engines ordered by name.
+func engineDefinitions() []EngineDefinition {
+	definitions := make([]EngineDefinition, 0, len(engines))
+	for _, definition := range engines {
+		definitions = append(definitions, definition)
+	}
+	slices.SortFunc(definitions, func(a, b EngineDefinition) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return definitions
+}
+
+// engineNames lists the engines for the usage of the -engine flag.
+func engineNames() string {
+	names := []string{}
+	for _, definition := range engineDefinitions() {
+		names = append(names, definition.Name)
+	}
+	return strings.Join(names, "/")
+}
@@ -3,3 +3,15 @@ package main
 type Sampler interface {
 	Sample(i int) Pair
 }
+
+// newSampler returns the sampler called name over a grid of n rows and m
+// columns of chunks.
+func newSampler(name string, n, m int) Sampler {
+	switch name {
+	case "hilbert":
+		return UnCachedHilbertCurveSampler{n: n, m: m}
+	case "cachedhilbert":
+		return NewHilbertCurveSampler(n, m)
+	}
+	return LinearSampler{n: n, m: m}
+}
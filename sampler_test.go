@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// gridShapes are chunk grids of n rows and m columns, powers of two as
+// verify requires, square or not.
+var gridShapes = [][2]int{{1, 1}, {1, 8}, {8, 1}, {2, 4}, {4, 2}, {4, 4}, {4, 16}, {16, 4}, {32, 32}}
+
+func TestSamplersVisitEveryChunkOnce(t *testing.T) {
+	for _, name := range []string{"linear", "hilbert", "cachedhilbert"} {
+		for _, shape := range gridShapes {
+			n, m := shape[0], shape[1]
+			t.Run(fmt.Sprintf("%s/%dx%d", name, n, m), func(t *testing.T) {
+				sampler := newSampler(name, n, m)
+
+				seen := map[Pair]int{}
+				for k := range n * m {
+					P := sampler.Sample(k)
+					if P.x < 0 || int(P.x) >= m || P.y < 0 || int(P.y) >= n {
+						t.Fatalf("sample %d is %v, outside the grid", k, P)
+					}
+					if previous, ok := seen[P]; ok {
+						t.Fatalf("samples %d and %d are both %v", previous, k, P)
+					}
+					seen[P] = k
+				}
+			})
+		}
+	}
+}
+
+func TestLinearSamplerOrder(t *testing.T) {
+	sampler := newSampler("linear", 2, 4)
+	for k, want := range []Pair{{0, 0}, {1, 0}, {2, 0}, {3, 0}, {0, 1}, {1, 1}, {2, 1}, {3, 1}} {
+		if got := sampler.Sample(k); got != want {
+			t.Errorf("Sample(%d) = %v, want %v", k, got, want)
+		}
+	}
+}
+
+// TestHilbertSamplersAreCurves checks that on square grids, where the curve
+// never leaves the grid, every chunk neighbours the one before.
+func TestHilbertSamplersAreCurves(t *testing.T) {
+	for _, name := range []string{"hilbert", "cachedhilbert"} {
+		for _, size := range []int{2, 4, 16} {
+			sampler := newSampler(name, size, size)
+			for k := 1; k < size*size; k++ {
+				P, Q := sampler.Sample(k-1), sampler.Sample(k)
+				if distance := abs(P.x-Q.x) + abs(P.y-Q.y); distance != 1 {
+					t.Fatalf("%s %dx%d: sample %d at %v follows %v", name, size, size, k, Q, P)
+				}
+			}
+		}
+	}
+}
+
+func TestHilbertSamplersAgree(t *testing.T) {
+	for _, shape := range gridShapes {
+		n, m := shape[0], shape[1]
+		cached, uncached := newSampler("cachedhilbert", n, m), newSampler("hilbert", n, m)
+		for k := range n * m {
+			if cached.Sample(k) != uncached.Sample(k) {
+				t.Fatalf("%dx%d: sample %d is %v cached but %v uncached", n, m, k, cached.Sample(k), uncached.Sample(k))
+			}
+		}
+	}
+}
+
+func abs(value int32) int32 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,127 @@
+package main
+
+import "math/cmplx"
+
+// seriesTolerance is the largest relative error of the series against a
+// probe's exactly iterated delta for which iterations are still skipped. The
+// orbits of pixels near the set amplify any error over hundreds of
+// iterations, so it lies close to the float64 precision.
+const seriesTolerance = 1e-14
+
+// seriesProbes is how many parts the grid of probes divides the view into
+// along either axis, with probes from border to border.
+const seriesProbes = 4
+
+// maxSeriesSkip bounds the skip for views lying entirely inside the set,
+// where the series never stops being valid.
+const maxSeriesSkip = 1 << 16
+
+// seriesCoefficients approximate a pixel's perturbation after n iterations
+// as a cubic in its offset d from the reference, δc for the Mandelbrot set
+// and δ₀ for Julia sets:
+//
+//	δₙ ≈ Aₙd + Bₙd² + Cₙd³
+type seriesCoefficients struct {
+	a, b, c complex128
+}
+
+func newSeriesCoefficients(julia bool) seriesCoefficients {
+	if julia {
+		return seriesCoefficients{a: 1}
+	}
+	return seriesCoefficients{}
+}
+
+// next advances the coefficients past the reference value Z, following
+// δₙ₊₁ = 2Zδₙ + δₙ² + δc.
+func (s seriesCoefficients) next(Z complex128, julia bool) seriesCoefficients {
+	n := seriesCoefficients{
+		a: 2 * Z * s.a,
+		b: 2*Z*s.b + s.a*s.a,
+		c: 2*Z*s.c + 2*s.a*s.b,
+	}
+	if !julia {
+		n.a += 1
+	}
+	return n
+}
+
+func (s seriesCoefficients) at(d complex128) complex128 {
+	return d * (s.a + d*(s.b+d*s.c))
+}
+
+// skipBySeries finds how many iterations the series approximates within
+// seriesTolerance for a grid of probes over the view, none of which escapes,
+// and starts every pixel at that iteration.
+func (f *PerturbationEngine) skipBySeries() {
+	var probes []complex128
+	for i := range seriesProbes + 1 {
+		for j := range seriesProbes + 1 {
+			px, py := i*f.width/seriesProbes, j*f.height/seriesProbes
+			if px != f.width/2 || py != f.height/2 {
+				probes = append(probes, complex(f.viewport.Offset(float64(px), float64(py))))
+			}
+		}
+	}
+
+	deltas := make([]complex128, len(probes))
+	if f.julia {
+		copy(deltas, probes)
+	}
+
+	series := newSeriesCoefficients(f.julia)
+	skip := 0
+	for skip < maxSeriesSkip {
+		f.extendOrbit(skip + 1)
+		if skip+1 >= len(f.orbit) {
+			break
+		}
+
+		// Perform checks Zₙ + δₙ for escape before iterating it, so stop at
+		// the first n any probe escapes at.
+		Z := f.orbit[skip]
+		escaped := false
+		for p := range probes {
+			if cmplx.Abs(Z+deltas[p]) > f.escapeRadius {
+				escaped = true
+				break
+			}
+		}
+		if escaped {
+			break
+		}
+
+		nextSeries := series.next(Z, f.julia)
+		valid := true
+		for p, d := range probes {
+			dc := d
+			if f.julia {
+				dc = 0
+			}
+			deltas[p] = 2*Z*deltas[p] + deltas[p]*deltas[p] + dc
+
+			if cmplx.Abs(nextSeries.at(d)-deltas[p]) > seriesTolerance*cmplx.Abs(deltas[p]) || cmplx.IsNaN(nextSeries.at(d)) {
+				valid = false
+				break
+			}
+		}
+		if !valid {
+			break
+		}
+
+		series = nextSeries
+		skip++
+	}
+
+	if skip == 0 {
+		return
+	}
+
+	f.forEachPixel(func(p int, px, py int32) {
+		delta := series.at(complex(f.viewport.Offset(float64(px), float64(py))))
+		f.dzr[p], f.dzi[p] = real(delta), imag(delta)
+		f.refIndex[p] = skip
+	})
+	f.iterations += skip
+	f.skippedIterations = skip
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+// TestSeriesApproximationKeepsEscapes checks that skipping iterations by the
+// series leaves every pixel escaping at the iteration it escapes at without,
+// over hundreds of iterations in the seahorse valley.
+func TestSeriesApproximationKeepsEscapes(t *testing.T) {
+	for _, view := range []struct {
+		name                    string
+		centerX, centerY, scale string
+		subiterations, rounds   int
+	}{
+		{"overview", "-0.75", "0", "1", 50, 1},
+		{"seahorse", "-0.743643887037151", "0.13182590420533", "1e5", 300, 2},
+		{"seahorse deeper", "-0.743643887037151", "0.13182590420533", "1e6", 300, 2},
+	} {
+		t.Run(view.name, func(t *testing.T) {
+			params := testParams("perturbation", 64, 64)
+			params.centerX, params.centerY, params.scale = view.centerX, view.centerY, view.scale
+			params.subiterations = view.subiterations
+
+			exact := render(params, view.rounds)
+			params.seriesApproximation = true
+			skipped := render(params, view.rounds)
+
+			for y := range int32(params.height) {
+				for x := range int32(params.width) {
+					got, want := skipped.GetExplodesAt(x, y), exact.GetExplodesAt(x, y)
+					// The series render runs as many iterations past the ones it
+					// skipped, in which it may catch pixels the other leaves.
+					if want == 0 && (got == 0 || got >= exact.GetIterations()+params.subiterations) {
+						continue
+					}
+					if got != want {
+						t.Fatalf("pixel (%d, %d) escaped at %d with the series, at %d without", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
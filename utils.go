@@ -3,68 +3,103 @@ package main
 import (
 	"image"
 	"image/color"
+	"math"
+	"os"
+	"slices"
 )
 
-func Create2D[T any](n, m int) [][]T {
-	result := make([][]T, n)
-	for i := range result {
-		result[i] = make([]T, m)
+func Elvis[T any](left *T, right T) T {
+	if left == nil {
+		return right
 	}
-	return result
+	return *left
+}
+
+func Ptr[T any](val T) *T {
+	return &val
 }
 
-func Create2DWithValue[T any](n, m int, value T) [][]T {
-	result := make([][]T, n)
-	for i := range result {
-		result[i] = make([]T, m)
+// cloneRGBA returns a copy of img that later writes to img leave alone.
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	clone := *img
+	clone.Pix = slices.Clone(img.Pix)
+	return &clone
+}
+
+func savePNG(path string, img image.Image, text map[string]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
 	}
-	for i := range n {
-		for j := range m {
-			result[i][j] = value
-		}
+
+	if err = EncodePNGWithText(f, img, text); err != nil {
+		f.Close()
+		return err
 	}
-	return result
+	return f.Close()
 }
 
-func Create4D[T any](n, m, a, b int) [][][][]T {
-	result := make([][][][]T, n)
-	for i := range result {
-		result[i] = make([][][]T, m)
-	}
+// smoothIteration returns the normalized iteration count
+// n + 1 - log₂(ln|zₙ| / ln R) of a point escaping the radius R at n, which is
+// continuous across iteration bands.
+func smoothIteration(n int, modulus, radius float64) float64 {
+	return smoothIterationOfDegree(n, modulus, radius, 2)
+}
 
-	for i := range n {
-		for j := range m {
-			val := Create2D[T](a, b)
-			result[i][j] = val
-		}
+// smoothIterationOfDegree generalizes smoothIteration to z = z^d + c, whose
+// orbits grow by a power of d rather than 2 per iteration.
+func smoothIterationOfDegree(n int, modulus, radius, degree float64) float64 {
+	if n <= 0 {
+		return float64(n)
 	}
-	return result
+	return max(float64(n)+1-math.Log(math.Log(modulus)/math.Log(radius))/math.Log(degree), 0)
 }
 
-func Create4DWithValue[T any](n, m, a, b int, value T) [][][][]T {
-	result := make([][][][]T, n)
-	for i := range result {
-		result[i] = make([][][]T, m)
+// imageUpdater paints pixel (px, py) of img.
+type imageUpdater func(img *image.RGBA, px, py int, colorRange ColorRangeConverer, colorPicker ColorOf, engine Engine)
+
+// imageUpdaterOf returns what paints the pixels of engine as params ask.
+func imageUpdaterOf(params cliParams, engine Engine) imageUpdater {
+	if _, ok := engine.(ColoringEngine); ok {
+		return updateImageColored
+	}
+	if params.render == "distance" {
+		return updateImageDistance
 	}
+	if params.smooth {
+		return updateImageSmooth
+	}
+	return updateImage
+}
 
-	for i := range n {
-		for j := range m {
-			val := Create2DWithValue[T](a, b, value)
-			result[i][j] = val
-		}
+func updateImageSmooth(img *image.RGBA, px, py int, colorRange ColorRangeConverer, colorPicker ColorOf, engine Engine) {
+	explodesAt := engine.GetSmoothExplodesAt(int32(px), int32(py))
+	if explodesAt <= 0 {
+		img.Set(int(px), int(py), color.Black)
+	} else {
+		fac := colorRange.Get(explodesAt, float64(engine.GetMaxExplodesAt()))
+		col := colorPicker.Get(fac)
+		img.Set(int(px), int(py), col)
 	}
-	return result
 }
 
-func Elvis[T any](left *T, right T) T {
-	if left == nil {
-		return right
+// updateImageDistance shades escaped pixels by their estimated distance to
+// the set, so filaments thinner than a pixel still come out dark and crisp.
+func updateImageDistance(img *image.RGBA, px, py int, colorRange ColorRangeConverer, colorPicker ColorOf, engine Engine) {
+	estimator, ok := engine.(DistanceEstimator)
+	if !ok || engine.GetExplodesAt(int32(px), int32(py)) <= 0 {
+		img.Set(int(px), int(py), color.Black)
+		return
 	}
-	return *left
+
+	distance := estimator.GetDistance(int32(px), int32(py)) / estimator.GetPixelSize()
+	fac := math.Pow(min(max(distance, 0), 1), 0.25)
+	img.Set(int(px), int(py), color.Gray{Y: uint8(255 * fac)})
 }
 
-func Ptr[T any](val T) *T {
-	return &val
+// updateImageColored lets the engine color the pixel, see ColoringEngine.
+func updateImageColored(img *image.RGBA, px, py int, colorRange ColorRangeConverer, colorPicker ColorOf, engine Engine) {
+	img.Set(px, py, engine.(ColoringEngine).GetColor(int32(px), int32(py), colorPicker))
 }
 
 func updateImage(img *image.RGBA, px, py int, colorRange ColorRangeConverer, colorPicker ColorOf, engine Engine) {
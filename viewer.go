@@ -0,0 +1,235 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Viewer displays the rendered image and reports mouse input in image
+// pixels, whatever size the window is stretched to. Dragging pans the image,
+// dragging with shift held selects a region to zoom into instead and
+// dragging with ctrl held rotates the view around its center.
+type Viewer struct {
+	widget.BaseWidget
+
+	image         *canvas.Image
+	selection     *canvas.Rectangle
+	handle        *canvas.Line
+	width, height int
+
+	// OnTapped receives the clicked pixel.
+	OnTapped func(px, py float64)
+	// OnScrolled receives the pixel under the cursor and whether the wheel
+	// was scrolled up.
+	OnScrolled func(px, py float64, up bool)
+	// OnPanned receives the distance in pixels a drag moved the image by.
+	OnPanned func(dx, dy float64)
+	// OnSelected receives the opposite corners, in pixels, of the selected
+	// region, which has the aspect ratio of the image.
+	OnSelected func(x0, y0, x1, y1 float64)
+	// OnRotated receives the angle in degrees a drag swept around the center
+	// of the image, counter-clockwise.
+	OnRotated func(degrees float64)
+
+	// dragLock guards the drag offset, which SetImage reads from the
+	// iterating goroutine.
+	dragLock     sync.Mutex
+	dragX, dragY float32
+
+	selecting              bool
+	selectStart, selectEnd fyne.Position
+	selectStarted          bool
+
+	rotating               bool
+	rotateStart, rotateEnd fyne.Position
+	rotateStarted          bool
+}
+
+func NewViewer(width, height int) *Viewer {
+	v := &Viewer{
+		image:  canvas.NewImageFromImage(image.NewRGBA(image.Rect(0, 0, width, height))),
+		width:  width,
+		height: height,
+	}
+	v.image.FillMode = canvas.ImageFillStretch
+
+	v.selection = canvas.NewRectangle(color.NRGBA{R: 255, G: 255, B: 255, A: 48})
+	v.selection.StrokeColor = color.White
+	v.selection.StrokeWidth = 1
+	v.selection.Hide()
+
+	v.handle = canvas.NewLine(color.White)
+	v.handle.StrokeWidth = 1
+	v.handle.Hide()
+
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+// SetImage shows img, snapping back an image left offset by a finished drag.
+func (v *Viewer) SetImage(img image.Image) {
+	v.dragLock.Lock()
+	v.image.Image = img
+	if v.dragX == 0 && v.dragY == 0 {
+		v.image.Move(fyne.NewPos(0, 0))
+	}
+	v.dragLock.Unlock()
+	v.image.Refresh()
+}
+
+func (v *Viewer) CreateRenderer() fyne.WidgetRenderer {
+	return &viewerRenderer{viewer: v}
+}
+
+// toPixels converts a distance in canvas units to image pixels.
+func (v *Viewer) toPixels(x, y float32) (float64, float64) {
+	size := v.Size()
+	return float64(x) / float64(size.Width) * float64(v.width), float64(y) / float64(size.Height) * float64(v.height)
+}
+
+func (v *Viewer) Tapped(ev *fyne.PointEvent) {
+	if v.OnTapped != nil {
+		v.OnTapped(v.toPixels(ev.Position.X, ev.Position.Y))
+	}
+}
+
+func (v *Viewer) Scrolled(ev *fyne.ScrollEvent) {
+	if v.OnScrolled != nil && ev.Scrolled.DY != 0 {
+		px, py := v.toPixels(ev.Position.X, ev.Position.Y)
+		v.OnScrolled(px, py, ev.Scrolled.DY > 0)
+	}
+}
+
+func (v *Viewer) MouseDown(ev *desktop.MouseEvent) {
+	v.selecting = ev.Modifier&fyne.KeyModifierShift != 0
+	v.rotating = !v.selecting && ev.Modifier&fyne.KeyModifierControl != 0
+}
+
+func (v *Viewer) MouseUp(*desktop.MouseEvent) {}
+
+func (v *Viewer) Dragged(ev *fyne.DragEvent) {
+	if v.selecting {
+		if !v.selectStarted {
+			v.selectStarted = true
+			v.selectStart = ev.Position.SubtractXY(ev.Dragged.DX, ev.Dragged.DY)
+		}
+		v.selectEnd = v.constrainSelection(ev.Position)
+
+		v.selection.Move(fyne.NewPos(min(v.selectStart.X, v.selectEnd.X), min(v.selectStart.Y, v.selectEnd.Y)))
+		v.selection.Resize(fyne.NewSize(abs32(v.selectEnd.X-v.selectStart.X), abs32(v.selectEnd.Y-v.selectStart.Y)))
+		v.selection.Show()
+		return
+	}
+
+	if v.rotating {
+		if !v.rotateStarted {
+			v.rotateStarted = true
+			v.rotateStart = ev.Position.SubtractXY(ev.Dragged.DX, ev.Dragged.DY)
+		}
+		v.rotateEnd = ev.Position
+
+		size := v.Size()
+		v.handle.Position1 = fyne.NewPos(size.Width/2, size.Height/2)
+		v.handle.Position2 = ev.Position
+		v.handle.Show()
+		v.handle.Refresh()
+		return
+	}
+
+	v.dragLock.Lock()
+	defer v.dragLock.Unlock()
+	v.dragX += ev.Dragged.DX
+	v.dragY += ev.Dragged.DY
+	v.image.Move(fyne.NewPos(v.dragX, v.dragY))
+}
+
+func (v *Viewer) DragEnd() {
+	if v.selectStarted {
+		v.selectStarted = false
+		v.selection.Hide()
+
+		x0, y0 := v.toPixels(v.selectStart.X, v.selectStart.Y)
+		x1, y1 := v.toPixels(v.selectEnd.X, v.selectEnd.Y)
+		if v.OnSelected != nil && x0 != x1 && y0 != y1 {
+			v.OnSelected(x0, y0, x1, y1)
+		}
+		return
+	}
+
+	if v.rotateStarted {
+		v.rotateStarted = false
+		v.handle.Hide()
+
+		// Screen y points down, so the angle counter-clockwise on screen is
+		// the negated one.
+		size := v.Size()
+		start := math.Atan2(float64(v.rotateStart.Y-size.Height/2), float64(v.rotateStart.X-size.Width/2))
+		end := math.Atan2(float64(v.rotateEnd.Y-size.Height/2), float64(v.rotateEnd.X-size.Width/2))
+		degrees := -math.Remainder(end-start, 2*math.Pi) * 180 / math.Pi
+		if v.OnRotated != nil && degrees != 0 {
+			v.OnRotated(degrees)
+		}
+		return
+	}
+
+	v.dragLock.Lock()
+	dx, dy := v.toPixels(v.dragX, v.dragY)
+	v.dragX, v.dragY = 0, 0
+	v.dragLock.Unlock()
+
+	if v.OnPanned != nil && (dx != 0 || dy != 0) {
+		v.OnPanned(dx, dy)
+	}
+}
+
+// constrainSelection moves the dragged corner so that the selection keeps
+// the aspect ratio of the image, growing along its longer side.
+func (v *Viewer) constrainSelection(end fyne.Position) fyne.Position {
+	size := v.Size()
+	if size.Width == 0 {
+		return end
+	}
+	aspect := size.Height / size.Width
+
+	dx := end.X - v.selectStart.X
+	dy := end.Y - v.selectStart.Y
+	side := max(abs32(dx), abs32(dy)/aspect)
+
+	return fyne.NewPos(v.selectStart.X+float32(math.Copysign(float64(side), float64(dx))), v.selectStart.Y+float32(math.Copysign(float64(side*aspect), float64(dy))))
+}
+
+func abs32(x float32) float32 {
+	return float32(math.Abs(float64(x)))
+}
+
+type viewerRenderer struct {
+	viewer *Viewer
+}
+
+// Layout only resizes the image, so that a drag in progress keeps it offset.
+func (r *viewerRenderer) Layout(size fyne.Size) {
+	r.viewer.image.Resize(size)
+}
+
+func (r *viewerRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(1, 1)
+}
+
+func (r *viewerRenderer) Refresh() {
+	r.viewer.image.Refresh()
+	r.viewer.selection.Refresh()
+	r.viewer.handle.Refresh()
+}
+
+func (r *viewerRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.viewer.image, r.viewer.selection, r.viewer.handle}
+}
+
+func (r *viewerRenderer) Destroy() {}
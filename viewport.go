@@ -0,0 +1,73 @@
+package main
+
+import "math"
+
+// Viewport maps image pixels to the complex plane: the image spans 3/scale
+// horizontally around (CenterX, CenterY), with square pixels, and shows the
+// plane rotated counter-clockwise by Angle degrees.
+type Viewport struct {
+	Width, Height    int
+	CenterX, CenterY float64
+	Scale            float64
+	Angle            float64
+}
+
+func NewViewport(width, height int, centerX, centerY, scale, angle float64) Viewport {
+	return Viewport{
+		Width:   width,
+		Height:  height,
+		CenterX: centerX,
+		CenterY: centerY,
+		Scale:   scale,
+		Angle:   angle,
+	}
+}
+
+// ScaleFactors returns the size of a pixel in the complex plane along each
+// axis.
+func (v Viewport) ScaleFactors() (float64, float64) {
+	scaleFactor := float64(3) / (float64(v.Width) * v.Scale)
+	return scaleFactor, scaleFactor
+}
+
+// Rotation returns the cosine and sine of the angle the offsets from the
+// center are rotated by.
+func (v Viewport) Rotation() (float64, float64) {
+	return rotationOf(v.Angle)
+}
+
+// rotationOf returns the cosine and sine of angle, given in degrees, exactly
+// (1, 0) for an unrotated view.
+func rotationOf(angle float64) (float64, float64) {
+	if angle == 0 {
+		return 1, 0
+	}
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	return cos, sin
+}
+
+// Offset returns how far pixel (px, py) lies from the center.
+func (v Viewport) Offset(px, py float64) (float64, float64) {
+	scaleFactorX, scaleFactorY := v.ScaleFactors()
+	dx := (px - float64(v.Width/2)) * scaleFactorX
+	dy := (py - float64(v.Height/2)) * scaleFactorY
+
+	cos, sin := v.Rotation()
+	return dx*cos - dy*sin, dx*sin + dy*cos
+}
+
+// ToPlane returns the point of the complex plane under pixel (px, py).
+func (v Viewport) ToPlane(px, py float64) (float64, float64) {
+	dx, dy := v.Offset(px, py)
+	return v.CenterX + dx, v.CenterY + dy
+}
+
+// ToPixel returns the pixel over the point (x, y) of the complex plane.
+func (v Viewport) ToPixel(x, y float64) (float64, float64) {
+	scaleFactorX, scaleFactorY := v.ScaleFactors()
+
+	cos, sin := v.Rotation()
+	dx := (x-v.CenterX)*cos + (y-v.CenterY)*sin
+	dy := (y-v.CenterY)*cos - (x-v.CenterX)*sin
+	return dx/scaleFactorX + float64(v.Width/2), dy/scaleFactorY + float64(v.Height/2)
+}